@@ -0,0 +1,39 @@
+// Copyright (c) 2014 Jason Goecke
+// apps.go
+
+package wit
+
+import (
+	"encoding/json"
+)
+
+// Apps represents the apps the access token can see (https://wit.ai/docs/http#get__apps_link)
+type Apps []App
+
+// App represents a single app within Apps
+type App struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Private   bool   `json:"private"`
+	CreatedAt string `json:"created_at"`
+	// Raw is the JSON the app was parsed from, including any fields this
+	// package does not know about
+	Raw json.RawMessage `json:"-"`
+}
+
+// Parses the JSON for Apps
+func (client *Client) parseApps(data []byte) (*Apps, error) {
+	apps := &Apps{}
+	err := client.decode(data, apps)
+	if err != nil {
+		return nil, err
+	}
+	raw := []json.RawMessage{}
+	if json.Unmarshal(data, &raw) == nil && len(raw) == len(*apps) {
+		for i := range raw {
+			(*apps)[i].Raw = raw[i]
+		}
+	}
+	return apps, nil
+}
@@ -0,0 +1,369 @@
+// Copyright (c) 2014 Jason Goecke
+// batch.go
+
+package wit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BatchOptions configures BatchMessage. The zero value processes one request
+// at a time, in any order, without rate limiting, retries or checkpointing.
+type BatchOptions struct {
+	// Concurrency is the number of requests in flight at once
+	Concurrency int
+	// Ordered delivers results in the order the requests were received, so a
+	// completed result waits for every earlier one to be delivered first
+	Ordered bool
+	// RequestsPerSecond limits the rate across all workers, unlimited when zero
+	RequestsPerSecond float64
+	// MaxRetries is the number of times a request failing with a network
+	// error, a 429 or a 5xx is retried
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubling on each
+	// further retry. Defaults to 500ms.
+	RetryBackoff time.Duration
+	// Progress is called after each request completes, from a single goroutine
+	Progress func(BatchProgress)
+	// CheckpointFile records each successful request's index. Requests
+	// already recorded there are skipped, so an interrupted job can be
+	// resumed by sending the same requests again.
+	CheckpointFile string
+}
+
+// BatchProgress represents the running totals of a batch
+type BatchProgress struct {
+	Completed int
+	Failed    int
+	Skipped   int
+	Retries   int
+}
+
+// BatchResult represents the outcome of a single request within a batch.
+// Index is the request's position in the input channel.
+type BatchResult struct {
+	Index    int
+	Request  MessageRequest
+	Message  *Message
+	Err      error
+	Attempts int
+	skipped  bool
+}
+
+// Represents a line in the checkpoint file
+type checkpoint struct {
+	Index int    `json:"index"`
+	MsgID string `json:"msg_id,omitempty"`
+}
+
+// BatchMessage processes text messages read from requests with a pool of
+// workers, sending a BatchResult for each one. The results channel is closed
+// once requests is closed and drained, or ctx is done. Once ctx is done no
+// further results are delivered, including, in Ordered mode, completed results
+// still waiting for an earlier one.
+//
+// A failure to open or write the checkpoint file is reported as a result with
+// an Index of -1, after the results completed so far, and stops the batch
+// since it could no longer be resumed properly.
+//
+//	requests := make(chan wit.MessageRequest)
+//	go func() {
+//		for _, text := range tickets {
+//			requests <- wit.MessageRequest{Query: text}
+//		}
+//		close(requests)
+//	}()
+//	options := &wit.BatchOptions{Concurrency: 8, RequestsPerSecond: 10, MaxRetries: 3}
+//	for result := range client.BatchMessage(ctx, requests, options) {
+//		...
+//	}
+func (client *Client) BatchMessage(ctx context.Context, requests <-chan MessageRequest, options *BatchOptions) <-chan BatchResult {
+	opts := BatchOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 500 * time.Millisecond
+	}
+	out := make(chan BatchResult)
+	go client.runBatch(ctx, requests, opts, out)
+	return out
+}
+
+func (client *Client) runBatch(ctx context.Context, requests <-chan MessageRequest, opts BatchOptions, out chan<- BatchResult) {
+	defer close(out)
+	// Cancelled to stop the workers early, while results are still sent
+	// until ctx is done
+	work, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done, file, err := openCheckpoint(opts.CheckpointFile)
+	if err != nil {
+		select {
+		case out <- BatchResult{Index: -1, Err: err}:
+		case <-ctx.Done():
+		}
+		return
+	}
+	if file != nil {
+		defer file.Close()
+	}
+
+	type job struct {
+		index   int
+		request MessageRequest
+	}
+	jobs := make(chan job)
+	results := make(chan BatchResult)
+	limiter := newRateLimiter(opts.RequestsPerSecond)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for index := 0; ; index++ {
+			var request MessageRequest
+			var ok bool
+			select {
+			case request, ok = <-requests:
+			case <-work.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+			if done[index] {
+				results <- BatchResult{Index: index, Request: request, skipped: true}
+				continue
+			}
+			select {
+			case jobs <- job{index, request}:
+			case <-work.Done():
+				return
+			}
+		}
+	}()
+	for worker := 0; worker < opts.Concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- client.batchOne(work, j.index, j.request, opts, limiter)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	progress := BatchProgress{}
+	pending := map[int]BatchResult{}
+	next := 0
+	for result := range results {
+		switch {
+		case result.skipped:
+			progress.Skipped++
+		case result.Err != nil:
+			progress.Failed++
+		default:
+			progress.Completed++
+			if file != nil {
+				err = writeCheckpoint(file, result)
+			}
+		}
+		if result.Attempts > 1 {
+			progress.Retries += result.Attempts - 1
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+
+		if !opts.Ordered {
+			if !result.skipped && !send(ctx, out, result) {
+				drain(results)
+				return
+			}
+		} else {
+			pending[result.Index] = result
+			for {
+				ready, found := pending[next]
+				if !found {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !ready.skipped && !send(ctx, out, ready) {
+					drain(results)
+					return
+				}
+			}
+		}
+
+		if err != nil {
+			// Stops the workers, delivering the completed results that were
+			// waiting for an earlier one before the checkpoint error
+			cancel()
+			drain(results)
+			for _, index := range slices.Sorted(maps.Keys(pending)) {
+				if !pending[index].skipped && !send(ctx, out, pending[index]) {
+					return
+				}
+			}
+			send(ctx, out, BatchResult{Index: -1, Err: err})
+			return
+		}
+	}
+}
+
+// Processes a single request, waiting on the rate limiter before each attempt
+// and backing off between retries
+func (client *Client) batchOne(ctx context.Context, index int, request MessageRequest, opts BatchOptions, limiter *rateLimiter) BatchResult {
+	result := BatchResult{Index: index, Request: request}
+	backoff := opts.RetryBackoff
+	for {
+		result.Attempts++
+		if err := limiter.wait(ctx); err != nil {
+			result.Err = err
+			return result
+		}
+		result.Message, result.Err = client.MessageWithContext(WithRetryCount(ctx, result.Attempts-1), &request)
+		if result.Err == nil || result.Attempts > opts.MaxRetries || !retryable(result.Err) || ctx.Err() != nil {
+			return result
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			result.Err = ctx.Err()
+			return result
+		}
+		backoff *= 2
+	}
+}
+
+// Reports whether a failed request is worth retrying: rate limiting, server
+// errors and network errors are, other API errors, invalid responses and
+// cancellations are not
+func retryable(err error) bool {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode == http.StatusTooManyRequests || apiError.StatusCode >= 500
+	}
+	// A truncated JSON body unwraps to io.ErrUnexpectedEOF as well
+	var parseError *ParseError
+	if errors.As(err, &parseError) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netError net.Error
+	return errors.As(err, &netError) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func send(ctx context.Context, out chan<- BatchResult, result BatchResult) bool {
+	select {
+	case out <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Lets the workers finish once the consumer has gone away
+func drain(results <-chan BatchResult) {
+	go func() {
+		for range results {
+		}
+	}()
+}
+
+// Opens checkpoint files, replaced in tests
+var openCheckpointFile = os.OpenFile
+
+// Reads the indexes already completed and opens the checkpoint for appending
+func openCheckpoint(path string) (map[int]bool, *os.File, error) {
+	done := map[int]bool{}
+	if path == "" {
+		return done, nil, nil
+	}
+	file, err := openCheckpointFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := checkpoint{}
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			done[entry.Index] = true
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return done, file, nil
+}
+
+// Appends a completed request to the checkpoint file
+func writeCheckpoint(file *os.File, result BatchResult) error {
+	line, err := json.Marshal(checkpoint{result.Index, result.Message.MsgID})
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Spaces requests evenly at a fixed rate across goroutines
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+// Blocks until the next request may be sent. A nil limiter never blocks.
+func (limiter *rateLimiter) wait(ctx context.Context) error {
+	if limiter == nil {
+		return ctx.Err()
+	}
+	limiter.mu.Lock()
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	delay := limiter.next.Sub(now)
+	limiter.next = limiter.next.Add(limiter.interval)
+	limiter.mu.Unlock()
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2014 Jason Goecke
+// batch_test.go
+
+package wit_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+// Sends /message requests to a fake Wit API through handler first
+func answerMessages(server *wittest.Server, handler func(w http.ResponseWriter, query string)) {
+	server.Intercept("GET", "/message", func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r.URL.Query().Get("q"))
+	})
+}
+
+func feed(queries ...string) <-chan wit.MessageRequest {
+	requests := make(chan wit.MessageRequest, len(queries))
+	for _, query := range queries {
+		requests <- wit.MessageRequest{Query: query}
+	}
+	close(requests)
+	return requests
+}
+
+func messageCalls(server *wittest.Server) int {
+	return len(server.Requested("GET", "/message"))
+}
+
+func TestBatchMessageOrdered(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	answerMessages(server, func(w http.ResponseWriter, query string) {
+		n, _ := strconv.Atoi(query)
+		time.Sleep(time.Duration(10-n) * 2 * time.Millisecond)
+	})
+	var queries []string
+	for i := 0; i < 10; i++ {
+		queries = append(queries, strconv.Itoa(i))
+	}
+
+	var updates []wit.BatchProgress
+	options := &wit.BatchOptions{Concurrency: 4, Ordered: true, Progress: func(progress wit.BatchProgress) {
+		updates = append(updates, progress)
+	}}
+	index := 0
+	for result := range client.BatchMessage(context.Background(), feed(queries...), options) {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if result.Index != index || result.Message.Text != strconv.Itoa(index) {
+			t.Errorf("Result %d delivered out of order as %d", result.Index, index)
+		}
+		index++
+	}
+	if index != 10 {
+		t.Errorf("not equal %d != %d", 10, index)
+	}
+	if len(updates) != 10 || updates[9].Completed != 10 {
+		t.Errorf("Unexpected progress %+v", updates)
+	}
+}
+
+func TestBatchMessageRetries(t *testing.T) {
+	var failures int32
+	server, client := wittest.NewTestServer(t)
+	answerMessages(server, func(w http.ResponseWriter, query string) {
+		switch {
+		case query == "flaky" && atomic.AddInt32(&failures, 1) <= 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case query == "dropped":
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case query == "missing":
+			w.WriteHeader(http.StatusNotFound)
+		case query == "limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case query == "garbled":
+			w.Write([]byte(`{"msg_id":`))
+		}
+	})
+
+	options := &wit.BatchOptions{Concurrency: 2, MaxRetries: 2, RetryBackoff: time.Millisecond}
+	results := map[string]wit.BatchResult{}
+	var last wit.BatchProgress
+	options.Progress = func(progress wit.BatchProgress) { last = progress }
+	for result := range client.BatchMessage(context.Background(), feed("flaky", "dropped", "missing", "limited", "garbled", "fine"), options) {
+		results[result.Request.Query] = result
+	}
+
+	if results["flaky"].Err != nil || results["flaky"].Attempts != 3 {
+		t.Errorf("Expected flaky to succeed on the third attempt: %+v", results["flaky"])
+	}
+	var netError net.Error
+	if !errors.As(results["dropped"].Err, &netError) || results["dropped"].Attempts != 3 {
+		t.Errorf("Expected the dropped connection to be retried and fail: %+v", results["dropped"])
+	}
+	if results["missing"].Err == nil || results["missing"].Attempts != 1 || results["missing"].Err.Error() != http.StatusText(404) {
+		t.Errorf("Expected missing to fail without retrying: %+v", results["missing"])
+	}
+	if results["limited"].Err == nil || results["limited"].Attempts != 3 {
+		t.Errorf("Expected limited to be retried and fail: %+v", results["limited"])
+	}
+	if _, ok := results["garbled"].Err.(*wit.ParseError); !ok || results["garbled"].Attempts != 1 {
+		t.Errorf("Expected an invalid response to fail without retrying: %+v", results["garbled"])
+	}
+	// The transport may resend a request on a dropped connection by itself
+	calls := 0
+	for _, request := range server.Requested("GET", "/message") {
+		if request.Query.Get("q") != "dropped" {
+			calls++
+		}
+	}
+	if calls != 9 {
+		t.Errorf("not equal %d != %d", 9, calls)
+	}
+	if last.Completed != 2 || last.Failed != 4 || last.Retries != 6 {
+		t.Errorf("Unexpected progress %+v", last)
+	}
+}
+
+func TestBatchMessageRateLimit(t *testing.T) {
+	_, client := wittest.NewTestServer(t)
+	start := time.Now()
+	count := 0
+	options := &wit.BatchOptions{Concurrency: 5, RequestsPerSecond: 50}
+	for result := range client.BatchMessage(context.Background(), feed("a", "b", "c", "d", "e", "f"), options) {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		count++
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("6 requests at 50/s took only %s", elapsed)
+	}
+	if count != 6 {
+		t.Errorf("not equal %d != %d", 6, count)
+	}
+}
+
+func TestBatchMessageCheckpoint(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	answerMessages(server, func(w http.ResponseWriter, query string) {
+		if query == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	server.AddMessage("c", &wit.Message{MsgID: "c"})
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	options := &wit.BatchOptions{Concurrency: 2, CheckpointFile: path}
+
+	for range client.BatchMessage(context.Background(), feed("a", "bad", "c"), options) {
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "\n") != 2 || !strings.Contains(string(data), `{"index":2,"msg_id":"c"}`) {
+		t.Errorf("Unexpected checkpoint:\n%s", data)
+	}
+
+	calls := messageCalls(server)
+	var indexes []int
+	var last wit.BatchProgress
+	options.Ordered = true
+	options.Progress = func(progress wit.BatchProgress) { last = progress }
+	for result := range client.BatchMessage(context.Background(), feed("a", "bad", "c", "d"), options) {
+		indexes = append(indexes, result.Index)
+	}
+	if len(indexes) != 2 || indexes[0] != 1 || indexes[1] != 3 {
+		t.Errorf("Expected only the failed and new requests, got %v", indexes)
+	}
+	if messageCalls(server)-calls != 2 || last.Skipped != 2 {
+		t.Errorf("Checkpointed requests were not skipped: %d calls, %+v", messageCalls(server)-calls, last)
+	}
+
+	results := client.BatchMessage(context.Background(), feed("a"), &wit.BatchOptions{CheckpointFile: filepath.Join(path, "nope")})
+	result := <-results
+	if result.Index != -1 || result.Err == nil {
+		t.Errorf("Expected a checkpoint error, got %+v", result)
+	}
+	if _, open := <-results; open {
+		t.Error("Results should be closed after a checkpoint error")
+	}
+}
+
+func TestBatchMessageCheckpointWriteError(t *testing.T) {
+	_, client := wittest.NewTestServer(t)
+	defer wit.SetCheckpointOpener(func(name string, flag int, perm os.FileMode) (*os.File, error) {
+		return os.OpenFile(name, os.O_RDONLY|os.O_CREATE, perm)
+	})()
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	options := &wit.BatchOptions{Concurrency: 2, Ordered: true, CheckpointFile: path}
+
+	var results []wit.BatchResult
+	for result := range client.BatchMessage(context.Background(), feed("a", "b", "c", "d", "e", "f"), options) {
+		results = append(results, result)
+	}
+	if len(results) == 0 || len(results) == 7 {
+		t.Fatalf("Expected the batch to stop on the write error, got %d results", len(results))
+	}
+	failure := results[len(results)-1]
+	if failure.Index != -1 || failure.Err == nil {
+		t.Errorf("Expected a checkpoint error last, got %+v", failure)
+	}
+	for i, result := range results[:len(results)-1] {
+		if result.Err != nil || (i > 0 && result.Index <= results[i-1].Index) {
+			t.Errorf("Expected completed results in order before the error, got %+v", results)
+		}
+	}
+}
+
+func TestBatchMessageCancel(t *testing.T) {
+	var once sync.Once
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, client := wittest.NewTestServer(t)
+	answerMessages(server, func(w http.ResponseWriter, query string) {
+		once.Do(cancel)
+	})
+
+	requests := make(chan wit.MessageRequest)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case requests <- wit.MessageRequest{Query: strconv.Itoa(i)}:
+			case <-time.After(time.Second):
+				close(requests)
+				return
+			}
+		}
+	}()
+	count := 0
+	for range client.BatchMessage(ctx, requests, &wit.BatchOptions{Concurrency: 2}) {
+		count++
+	}
+	if count > 2 {
+		t.Errorf("Expected the batch to stop after cancelling, got %d results", count)
+	}
+}
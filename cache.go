@@ -0,0 +1,321 @@
+// Copyright (c) 2014 Jason Goecke
+// cache.go
+
+package wit
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// Cache represents a store of processed messages keyed by MessageCache
+type Cache interface {
+	Get(key string) (*Message, bool)
+	Set(key string, message *Message)
+}
+
+// MessageCache sits in front of Message when set as Client.MessageCache.
+// Requests are keyed on their normalized query text, context, n, dynamic
+// entities, Tag and the API version, and concurrent identical requests share
+// a single call to Wit. Requests with a MsgID are never cached.
+//
+// Every caller gets its own copy of a message. A message answering a query
+// spelled differently, e.g. "Hi" for "hi ", has its text and entity spans
+// mapped onto the caller's query; when a span cannot be mapped the request is
+// sent to Wit instead.
+//
+//		client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(1000, time.Hour), "v42")
+type MessageCache struct {
+	Cache Cache
+	// Tag identifies the Wit app or model version so a new version does not
+	// serve answers cached from the last one
+	Tag string
+	// Timeout bounds a call to Wit shared by concurrent requests, which is not
+	// cancelled when one of them gives up. Defaults to 30s.
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]*flight
+	hits     int64
+	misses   int64
+	shared   int64
+}
+
+// CacheStats represents the counters of a MessageCache. Shared counts the
+// misses that waited on an identical request already in flight.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	Shared int64
+}
+
+// Represents a call to Wit that concurrent identical requests wait on
+type flight struct {
+	done     chan struct{}
+	message  *Message
+	err      error
+	response Response
+}
+
+// NewMessageCache creates a MessageCache storing messages in cache
+//
+//		messageCache := wit.NewMessageCache(wit.NewLRUCache(1000, time.Hour), "")
+func NewMessageCache(cache Cache, tag string) *MessageCache {
+	return &MessageCache{Cache: cache, Tag: tag, inflight: map[string]*flight{}}
+}
+
+// Stats returns the hit and miss counters
+//
+//		stats := client.MessageCache.Stats()
+func (messageCache *MessageCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&messageCache.hits),
+		Misses: atomic.LoadInt64(&messageCache.misses),
+		Shared: atomic.LoadInt64(&messageCache.shared),
+	}
+}
+
+// HitRate returns the fraction of lookups served from the cache
+func (stats CacheStats) HitRate() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// Returns the cached message for a request, or calls fetch once for all
+// concurrent callers of the same key and caches a successful result. The call
+// outlives callers giving up, so that the others still get an answer.
+func (messageCache *MessageCache) message(ctx context.Context, request *MessageRequest, fetch func(context.Context, *MessageRequest) (*Message, error)) (*Message, error) {
+	key, err := messageCache.key(request)
+	if err != nil {
+		return nil, err
+	}
+	if cached, found := messageCache.Cache.Get(key); found {
+		if message, ok := answer(cached, request.Query); ok {
+			atomic.AddInt64(&messageCache.hits, 1)
+			return message, nil
+		}
+	}
+	atomic.AddInt64(&messageCache.misses, 1)
+
+	messageCache.mu.Lock()
+	if messageCache.inflight == nil {
+		messageCache.inflight = map[string]*flight{}
+	}
+	call, found := messageCache.inflight[key]
+	if found {
+		atomic.AddInt64(&messageCache.shared, 1)
+	} else {
+		call = &flight{done: make(chan struct{})}
+		messageCache.inflight[key] = call
+		go messageCache.fly(ctx, key, *request, call, fetch)
+	}
+	messageCache.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if response, ok := ctx.Value(responseKey{}).(*Response); ok && response != nil && call.response.StatusCode != 0 {
+		*response = call.response
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	if message, ok := answer(call.message, request.Query); ok {
+		return message, nil
+	}
+	return fetch(ctx, request)
+}
+
+// Makes the call to Wit shared by a flight, detached from the cancellation of
+// the caller that started it
+func (messageCache *MessageCache) fly(ctx context.Context, key string, request MessageRequest, call *flight, fetch func(context.Context, *MessageRequest) (*Message, error)) {
+	timeout := messageCache.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	call.message, call.err = fetch(WithResponse(ctx, &call.response), &request)
+	if call.err == nil {
+		messageCache.Cache.Set(key, call.message)
+	}
+	messageCache.mu.Lock()
+	delete(messageCache.inflight, key)
+	messageCache.mu.Unlock()
+	close(call.done)
+}
+
+// Returns a copy of a cached message answering query, which may be spelled
+// differently from the query the message was fetched for
+func answer(cached *Message, query string) (*Message, bool) {
+	if cached.Text == query || cached.Text == "" {
+		return cached.copy(), true
+	}
+	offset, ok := wordOffsets(cached.Text, query)
+	if !ok {
+		return nil, false
+	}
+	return cached.rewrite(cached.Text, query, func(entity *MessageEntity) bool {
+		start, startOK := offset(int(*entity.Start))
+		end, endOK := offset(int(*entity.End))
+		if !startOK || !endOK || start > end {
+			return false
+		}
+		start64, end64 := int64(start), int64(end)
+		entity.Start, entity.End = &start64, &end64
+		if entity.Body != nil {
+			body := query[start:end]
+			entity.Body = &body
+		}
+		return true
+	})
+}
+
+// Maps byte offsets of from onto to, two texts with the same words apart from
+// their case and the whitespace between them. Offsets inside a word whose
+// length changed with its case, or inside whitespace, cannot be mapped.
+func wordOffsets(from string, to string) (func(int) (int, bool), bool) {
+	fromWords, toWords := wordBounds(from), wordBounds(to)
+	if len(fromWords) != len(toWords) {
+		return nil, false
+	}
+	return func(offset int) (int, bool) {
+		for i, word := range fromWords {
+			target := toWords[i]
+			switch {
+			case offset == word[0]:
+				return target[0], true
+			case offset == word[1]:
+				return target[1], true
+			case offset > word[0] && offset < word[1] && word[1]-word[0] == target[1]-target[0]:
+				return target[0] + offset - word[0], true
+			}
+		}
+		return 0, false
+	}, true
+}
+
+// Returns the byte offsets of the start and end of each word of text, as
+// split by strings.Fields
+func wordBounds(text string) [][2]int {
+	var bounds [][2]int
+	start := -1
+	for i, c := range text {
+		switch {
+		case unicode.IsSpace(c) && start >= 0:
+			bounds = append(bounds, [2]int{start, i})
+			start = -1
+		case !unicode.IsSpace(c) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		bounds = append(bounds, [2]int{start, len(text)})
+	}
+	return bounds
+}
+
+// Builds the cache key for a request
+func (messageCache *MessageCache) key(request *MessageRequest) (string, error) {
+	data, err := json.Marshal(struct {
+		Tag      string          `json:"tag"`
+		Version  string          `json:"version"`
+		Query    string          `json:"q"`
+		Context  *Context        `json:"context"`
+		N        int             `json:"n"`
+		Entities DynamicEntities `json:"entities"`
+	}{messageCache.Tag, APIVersion, normalizeQuery(request.Query), request.Context, request.N, request.Entities})
+	return string(data), err
+}
+
+// Lower cases the query and collapses its whitespace so trivially different
+// spellings of the same text share a cache entry
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// LRUCache is an in-memory Cache holding at most Capacity messages, each for
+// at most TTL. It is safe for concurrent use.
+type LRUCache struct {
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	message *Message
+	expires time.Time
+}
+
+// NewLRUCache creates an LRUCache. A zero ttl keeps messages until they are evicted.
+//
+//		cache := wit.NewLRUCache(1000, time.Hour)
+func NewLRUCache(capacity int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+// Get implements Cache
+func (cache *LRUCache) Get(key string) (*Message, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	element, found := cache.entries[key]
+	if !found {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if cache.ttl > 0 && !cache.now().Before(entry.expires) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return entry.message, true
+}
+
+// Set implements Cache, evicting the least recently used message when full
+func (cache *LRUCache) Set(key string, message *Message) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	expires := cache.now().Add(cache.ttl)
+	if element, found := cache.entries[key]; found {
+		entry := element.Value.(*lruEntry)
+		entry.message, entry.expires = message, expires
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(&lruEntry{key, message, expires})
+	for cache.capacity > 0 && cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of messages held, including any that have expired
+// but not yet been looked up
+func (cache *LRUCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
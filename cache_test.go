@@ -0,0 +1,257 @@
+// Copyright (c) 2014 Jason Goecke
+// cache_test.go
+
+package wit_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+// Answers /message on a fake Wit API with a request ID derived from the
+// query, failing for "fail". Requests wait for release to be closed when it
+// is set.
+func interceptMessages(server *wittest.Server, release chan struct{}) {
+	server.Intercept("GET", "/message", func(w http.ResponseWriter, r *http.Request) {
+		if release != nil {
+			<-release
+		}
+		w.Header().Set(wit.RequestIDHeader, "request-"+r.URL.Query().Get("q"))
+		if r.URL.Query().Get("q") == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+}
+
+// Keeps the messages reported to Metrics
+type messageRecorder struct {
+	mu       sync.Mutex
+	messages []*wit.Message
+}
+
+func (recorder *messageRecorder) ObserveRequest(observation *wit.RequestObservation) {}
+
+func (recorder *messageRecorder) ObserveMessage(message *wit.Message) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.messages = append(recorder.messages, message)
+}
+
+func TestMessageCache(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	interceptMessages(server, nil)
+	client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(10, time.Minute), "v1")
+	recorder := &messageRecorder{}
+	client.Metrics = recorder
+
+	for _, query := range []string{"Hi", "hi", "  HI  ", "hi"} {
+		message, err := client.Message(&wit.MessageRequest{Query: query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if message.Text != query {
+			t.Errorf("not equal %s != %s", query, message.Text)
+		}
+	}
+	client.Message(&wit.MessageRequest{Query: "hi", Context: &wit.Context{Timezone: "Europe/Paris"}})
+	client.Message(&wit.MessageRequest{Query: "hi", N: 2})
+	client.Message(&wit.MessageRequest{Query: "hi", MsgID: "custom"})
+	client.Message(&wit.MessageRequest{Query: "hi", MsgID: "custom"})
+	if calls := messageCalls(server); calls != 5 {
+		t.Errorf("not equal %d != %d", 5, calls)
+	}
+	if len(recorder.messages) != 8 {
+		t.Errorf("Every message should be observed, cached or not, got %d", len(recorder.messages))
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Message(&wit.MessageRequest{Query: "fail"}); err == nil {
+			t.Error("Expected an error")
+		}
+	}
+	if messageCalls(server) != 7 {
+		t.Error("Errors should not be cached")
+	}
+
+	stats := client.MessageCache.Stats()
+	if stats.Hits != 3 || stats.Misses != 5 || stats.HitRate() != 3.0/8 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	client.MessageCache.Tag = "v2"
+	client.Message(&wit.MessageRequest{Query: "hi"})
+	if messageCalls(server) != 8 {
+		t.Error("A new tag should not share cached messages")
+	}
+}
+
+func TestMessageCacheCopies(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	interceptMessages(server, nil)
+	client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(10, time.Minute), "v1")
+	server.Script("GET", "/message", 200, `{"msg_id":"1","_text":"Flights to  Paris","outcomes":[{"_text":"Flights to  Paris","intent":"book_flight","confidence":0.9,
+		"entities":{"location":[{"value":"Paris","body":"Paris","start":12,"end":17,"suggested":true}]}}]}`)
+
+	first, err := client.Message(&wit.MessageRequest{Query: "Flights to  Paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Outcomes[0].Intent = "modified"
+	*first.Outcomes[0].Entities["location"][0].Start = 0
+
+	message, err := client.Message(&wit.MessageRequest{Query: "flights to paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageCalls(server) != 1 {
+		t.Fatalf("Expected a cache hit, got %d calls", messageCalls(server))
+	}
+	outcome := message.Outcomes[0]
+	location := outcome.Entities["location"][0]
+	if message.Text != "flights to paris" || outcome.Text != "flights to paris" || outcome.Intent != "book_flight" {
+		t.Errorf("Unexpected message %+v", message)
+	}
+	if *location.Start != 11 || *location.End != 16 || *location.Body != "paris" || (*location.Value).(string) != "Paris" {
+		t.Errorf("Entity span was not mapped onto the query: %d-%d %s", *location.Start, *location.End, *location.Body)
+	}
+	if !strings.Contains(string(message.Raw), `"_text":"flights to paris"`) ||
+		string(location.Raw) != `{"body":"paris","end":16,"start":11,"suggested":true,"value":"Paris"}` {
+		t.Errorf("Raw was not rebuilt: %s", message.Raw)
+	}
+	if spans := message.Spans(); len(spans) != 1 || spans[0].Text != "paris" {
+		t.Errorf("Unexpected spans %+v", spans)
+	}
+}
+
+func TestMessageCacheUnmappedSpan(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	interceptMessages(server, nil)
+	client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(10, time.Minute), "v1")
+	server.Script("GET", "/message", 200, `{"msg_id":"1","_text":"to  Paris","outcomes":[{"entities":{"location":[{"value":"Paris","start":3,"end":9}]}}]}`)
+
+	if _, err := client.Message(&wit.MessageRequest{Query: "to  Paris"}); err != nil {
+		t.Fatal(err)
+	}
+	message, err := client.Message(&wit.MessageRequest{Query: "to paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageCalls(server) != 2 || message.Text != "to paris" {
+		t.Errorf("A span starting in whitespace should not be mapped: %d calls, %+v", messageCalls(server), message)
+	}
+	if stats := client.MessageCache.Stats(); stats.Hits != 0 || stats.Misses != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestMessageCacheSingleflight(t *testing.T) {
+	release := make(chan struct{})
+	server, client := wittest.NewTestServer(t)
+	interceptMessages(server, release)
+	client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(10, time.Minute), "v1")
+
+	var wg sync.WaitGroup
+	responses := make([]wit.Response, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := wit.WithResponse(context.Background(), &responses[i])
+			if _, err := client.MessageWithContext(ctx, &wit.MessageRequest{Query: "cancel"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	for client.MessageCache.Stats().Shared < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls := messageCalls(server); calls != 1 {
+		t.Errorf("not equal %d != %d", 1, calls)
+	}
+	if stats := client.MessageCache.Stats(); stats.Shared != 4 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	for _, response := range responses {
+		if response.StatusCode != 200 || response.RequestID != "request-cancel" {
+			t.Errorf("Shared response not recorded: %+v", response)
+		}
+	}
+}
+
+func TestMessageCacheLeaderCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server, client := wittest.NewTestServer(t)
+	interceptMessages(server, release)
+	client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(10, time.Minute), "v1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := client.MessageWithContext(ctx, &wit.MessageRequest{Query: "hello"})
+		leader <- err
+	}()
+	for messageCalls(server) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	waiter := make(chan *wit.Message)
+	go func() {
+		message, err := client.Message(&wit.MessageRequest{Query: "hello"})
+		if err != nil {
+			t.Error(err)
+		}
+		waiter <- message
+	}()
+	for client.MessageCache.Stats().Shared < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-leader; err != context.Canceled {
+		t.Errorf("Expected the cancelled caller to give up, got %v", err)
+	}
+	close(release)
+	if message := <-waiter; message == nil || message.Text != "hello" {
+		t.Errorf("The waiting caller did not get the shared answer: %+v", message)
+	}
+	if _, err := client.Message(&wit.MessageRequest{Query: "hello"}); err != nil || messageCalls(server) != 1 {
+		t.Errorf("The shared answer was not cached: %v, %d calls", err, messageCalls(server))
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	now := time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)
+	cache := wit.NewLRUCache(2, time.Minute)
+	cache.SetClock(func() time.Time { return now })
+
+	cache.Set("a", &wit.Message{MsgID: "a"})
+	cache.Set("b", &wit.Message{MsgID: "b"})
+	cache.Get("a")
+	cache.Set("c", &wit.Message{MsgID: "c"})
+	if _, found := cache.Get("b"); found {
+		t.Error("Least recently used entry should have been evicted")
+	}
+	if message, found := cache.Get("a"); !found || message.MsgID != "a" {
+		t.Error("Recently used entry should have been kept")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("not equal %d != %d", 2, cache.Len())
+	}
+
+	now = now.Add(time.Minute)
+	if _, found := cache.Get("c"); found {
+		t.Error("Expired entry should not be returned")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("not equal %d != %d", 1, cache.Len())
+	}
+}
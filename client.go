@@ -5,14 +5,15 @@ package wit
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
-	"net/http/httputil"
 	"os"
 	"regexp"
+	"time"
 )
 
 const (
@@ -25,6 +26,30 @@ const (
 // Client represents a client for the Wit API (https://wit.ai/docs/api)
 type Client struct {
 	APIBase string
+	// HTTPClient is used to send requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// MessageCache answers repeated Message requests when set
+	MessageCache *MessageCache
+	// Redactor masks personal information in Message queries before they are
+	// sent, and before they reach the MessageCache, when set
+	Redactor *Redactor
+	// Metrics receives a measurement of every request and parsed message when set
+	Metrics Metrics
+	// Tracer starts a span for every request when set
+	Tracer Tracer
+	// Logger receives requests at debug level, failed responses at warn level
+	// and transport errors at error level. Nothing is logged when nil, unless
+	// GOWIT_DEBUG=true.
+	Logger *slog.Logger
+	// LogBodyLimit truncates logged bodies, DefaultLogBodyLimit when zero and
+	// unlimited when negative
+	LogBodyLimit int
+	// RedactUserText keeps message text and context, dynamic entities, and the
+	// bodies and values of message entities out of logs
+	RedactUserText bool
+	// Strict rejects responses with fields this package does not know about,
+	// to notice when the API changes shape
+	Strict bool
 }
 
 // HTTPParams represents the HTTP parameters to pass along to the Wit API
@@ -35,6 +60,31 @@ type HTTPParams struct {
 	Data        []byte
 }
 
+// APIError represents a non 200 response from the Wit API. Its message is the
+// HTTP status text, e.g. "Not Found".
+type APIError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (err *APIError) Error() string {
+	return http.StatusText(err.StatusCode)
+}
+
+// ParseError represents a response body that could not be decoded
+type ParseError struct {
+	Body []byte
+	Err  error
+}
+
+func (err *ParseError) Error() string {
+	return "wit: invalid response: " + err.Err.Error()
+}
+
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
 // Stores the ApiKey for the Wit API
 var APIKey string
 
@@ -49,39 +99,52 @@ func NewClient(apiKey string) *Client {
 
 // Provides a common facility for doing a DELETE on a Wit resource
 //
-//		result, err := delete("https://api.wit.ai/entities", "favorite_city")
-func delete(resource string, id string) ([]byte, error) {
+//		result, err := client.delete(ctx, "https://api.wit.ai/entities", "favorite_city")
+func (client *Client) delete(ctx context.Context, resource string, id string) ([]byte, error) {
 	httpParams := &HTTPParams{
 		Resource: resource + "/" + id,
 		Verb:     "DELETE",
 	}
-	return processRequest(httpParams)
+	return client.processRequest(ctx, httpParams)
 }
 
 // Provides a common facility for doing a GET on a Wit resource
 //
-//		result, err := get("https://api.wit.ai/entities/favorite_city")
-func get(resource string) ([]byte, error) {
+//		result, err := client.get(ctx, "https://api.wit.ai/entities/favorite_city")
+func (client *Client) get(ctx context.Context, resource string) ([]byte, error) {
+	httpParams := &HTTPParams{
+		Resource: resource,
+		Verb:     "GET",
+	}
+	return client.processRequest(ctx, httpParams)
+}
+
+// Provides a common facility for doing a GET on a Wit resource answered by a
+// message
+//
+//		message, err := client.getMessage(ctx, "https://api.wit.ai/messages/ba0fcf60")
+func (client *Client) getMessage(ctx context.Context, resource string) (*Message, error) {
 	httpParams := &HTTPParams{
 		Resource: resource,
 		Verb:     "GET",
 	}
-	return processRequest(httpParams)
+	return client.processMessageRequest(ctx, httpParams)
 }
 
 // Provides a common facility for doing a POST on a Wit resource. Takes
 // JSON []byte for the data argument.
 //
-//		result, err := post("https://api.wit.ai/entities", entity)
-func post(resource string, data []byte) ([]byte, error) {
+//		result, err := client.post(ctx, "https://api.wit.ai/entities", entity)
+func (client *Client) post(ctx context.Context, resource string, data []byte) ([]byte, error) {
 	httpParams := &HTTPParams{"POST", resource, "application/json", data}
-	return processRequest(httpParams)
+	return client.processRequest(ctx, httpParams)
 }
 
-// Provides a common facility for doing a POST with a file on a Wit resource.
+// Provides a common facility for doing a POST with a file on a Wit resource
+// answered by a message.
 //
-//		result, err := postFile("https://api.wit.ai/messages", message)
-func postFile(resource string, request *MessageRequest) ([]byte, error) {
+//		message, err := client.postFile(ctx, "https://api.wit.ai/speech", request)
+func (client *Client) postFile(ctx context.Context, resource string, request *MessageRequest) (*Message, error) {
 	if request.File != "" {
 		file, err := os.Open(request.File)
 		if err != nil {
@@ -96,12 +159,12 @@ func postFile(resource string, request *MessageRequest) ([]byte, error) {
 		data := make([]byte, size)
 		file.Read(data)
 		httpParams := &HTTPParams{"POST", resource, request.ContentType, data}
-		return processRequest(httpParams)
+		return client.processMessageRequest(ctx, httpParams)
 	}
 
 	if request.FileContents != nil {
 		httpParams := &HTTPParams{"POST", resource, request.ContentType, request.FileContents}
-		return processRequest(httpParams)
+		return client.processMessageRequest(ctx, httpParams)
 		// } else {
 		// return nil, errors.New("Must provide a filename or contents")
 	}
@@ -111,14 +174,28 @@ func postFile(resource string, request *MessageRequest) ([]byte, error) {
 
 // Provides a common facility for doing a PUT on a Wit resource.
 //
-//		result, err := put("https://api.wit.ai/entities", entity)
-func put(resource string, data []byte) ([]byte, error) {
+//		result, err := client.put(ctx, "https://api.wit.ai/entities", entity)
+func (client *Client) put(ctx context.Context, resource string, data []byte) ([]byte, error) {
 	httpParams := &HTTPParams{"PUT", resource, "application/json", data}
-	return processRequest(httpParams)
+	return client.processRequest(ctx, httpParams)
 }
 
 // Processes an HTTP request to the Wit API
-func processRequest(httpParams *HTTPParams) ([]byte, error) {
+func (client *Client) processRequest(ctx context.Context, httpParams *HTTPParams) ([]byte, error) {
+	body, _, err := client.send(ctx, httpParams, false)
+	return body, err
+}
+
+// Processes an HTTP request to the Wit API answered by a message, parsing it
+// before the request's span ends so the span carries the msg_id and intent
+func (client *Client) processMessageRequest(ctx context.Context, httpParams *HTTPParams) (*Message, error) {
+	_, message, err := client.send(ctx, httpParams, true)
+	return message, err
+}
+
+// Sends an HTTP request to the Wit API, parsing a successful response as a
+// message when parseMessage is set
+func (client *Client) send(ctx context.Context, httpParams *HTTPParams, parseMessage bool) ([]byte, *Message, error) {
 	regex := regexp.MustCompile(`\?`)
 	if regex.MatchString(httpParams.Resource) {
 		httpParams.Resource += "&" + APIVersion
@@ -126,32 +203,104 @@ func processRequest(httpParams *HTTPParams) ([]byte, error) {
 		httpParams.Resource += "?" + APIVersion
 	}
 	reader := bytes.NewReader(httpParams.Data)
-	httpClient := &http.Client{}
-	req, err := http.NewRequest(httpParams.Verb, httpParams.Resource, reader)
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	ctx, span := client.startSpan(ctx, httpParams)
+	req, err := http.NewRequestWithContext(ctx, httpParams.Verb, httpParams.Resource, reader)
 	if err != nil {
-		return nil, err
+		endSpan(span, 0, nil, err)
+		return nil, nil, err
 	}
 	setHeaders(req, httpParams.ContentType)
-
-	if os.Getenv("GOWIT_DEBUG") == "true" {
-		debug(httputil.DumpRequestOut(req, true))
+	logger := client.logger()
+	if logger != nil {
+		client.logRequest(ctx, logger, req, httpParams)
 	}
 
+	start := time.Now()
 	result, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
-	}
-
-	if os.Getenv("GOWIT_DEBUG") == "true" {
-		debug(httputil.DumpResponse(result, true))
+		if logger != nil {
+			client.logError(ctx, logger, req, err, time.Since(start))
+		}
+		client.observeRequest(httpParams, start, 0, 0, err)
+		endSpan(span, 0, nil, err)
+		return nil, nil, err
 	}
 
 	body, err := ioutil.ReadAll(result.Body)
 	result.Body.Close()
+	recordResponse(ctx, result, time.Since(start))
+	if err != nil {
+		if logger != nil {
+			client.logError(ctx, logger, req, err, time.Since(start))
+		}
+		client.observeRequest(httpParams, start, result.StatusCode, len(body), err)
+		endSpan(span, result.StatusCode, nil, err)
+		return nil, nil, err
+	}
+	if logger != nil {
+		client.logResponse(ctx, logger, req, result, body, time.Since(start))
+	}
+	client.observeRequest(httpParams, start, result.StatusCode, len(body), nil)
 	if result.StatusCode != 200 {
-		return nil, errors.New(http.StatusText(result.StatusCode))
+		apiErr := &APIError{StatusCode: result.StatusCode, Body: body}
+		endSpan(span, result.StatusCode, nil, apiErr)
+		return nil, nil, apiErr
+	}
+	if !parseMessage {
+		endSpan(span, result.StatusCode, nil, nil)
+		return body, nil, nil
+	}
+	message, err := client.parseMessage(body)
+	endSpan(span, result.StatusCode, message, err)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, message, nil
+}
+
+// Reports a completed request to the client's Metrics, if any
+func (client *Client) observeRequest(httpParams *HTTPParams, start time.Time, statusCode int, size int, err error) {
+	if client.Metrics == nil {
+		return
+	}
+	client.Metrics.ObserveRequest(&RequestObservation{
+		Endpoint:      endpoint(httpParams.Resource),
+		Verb:          httpParams.Verb,
+		StatusCode:    statusCode,
+		ErrorClass:    errorClass(statusCode, err),
+		Duration:      time.Since(start),
+		ResponseBytes: size,
+	})
+}
+
+// Reports a parsed message to the client's Metrics, if any
+func (client *Client) observeMessage(message *Message) {
+	if client.Metrics != nil && message != nil {
+		client.Metrics.ObserveMessage(message)
 	}
-	return body, nil
+}
+
+// Decodes a response body into v, rejecting trailing data and, in Strict
+// mode, unknown fields
+//
+//		err := client.decode(result, message)
+func (client *Client) decode(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if client.Strict {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(v)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the JSON value")
+	}
+	if err != nil {
+		return &ParseError{Body: data, Err: err}
+	}
+	return nil
 }
 
 // Sets the custom headers required for the Wit.ai API
@@ -162,15 +311,3 @@ func setHeaders(req *http.Request, contentType string) {
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Accept", "application/json")
 }
-
-func debug(data []byte, err error) {
-	if err == nil {
-		if len(data) > 1000 {
-			fmt.Printf("DATA TOO LARGE %d\n\n", len(data))
-		} else {
-			fmt.Printf("%s\n\n", data)
-		}
-	} else {
-		log.Fatalf("%s\n\n", err)
-	}
-}
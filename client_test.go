@@ -1,6 +1,194 @@
 // Copyright (c) 2014 Jason Goecke
 // client_test.go
 
-package wit
+package wit_test
 
-// Test coverage in entities_test.og, intents_test.go and messages_test.go right now.
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+// Answers body to every call of decodingCalls and to the list endpoints of a
+// fake Wit API
+func answerAll(server *wittest.Server, body string) {
+	routes := [][2]string{
+		{"GET", "/message"},
+		{"GET", "/messages/ba0fcf60"},
+		{"POST", "/speech"},
+		{"GET", "/intents"},
+		{"GET", "/entities"},
+		{"GET", "/entities/city"},
+		{"POST", "/entities"},
+		{"POST", "/entities/city/values"},
+		{"POST", "/entities/city/values/Paris/expressions"},
+	}
+	for _, route := range routes {
+		server.Intercept(route[0], route[1], func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		})
+	}
+}
+
+// Calls every API that decodes a response
+func decodingCalls(client *wit.Client) map[string]func() error {
+	return map[string]func() error{
+		"Message": func() error {
+			_, err := client.Message(&wit.MessageRequest{Query: "hello"})
+			return err
+		},
+		"Messages": func() error {
+			_, err := client.Messages("ba0fcf60")
+			return err
+		},
+		"AudioMessage": func() error {
+			_, err := client.AudioMessage(&wit.MessageRequest{FileContents: []byte("RIFF"), ContentType: "audio/wav"})
+			return err
+		},
+		"Intents": func() error {
+			_, err := client.Intents()
+			return err
+		},
+		"Entities": func() error {
+			_, err := client.Entities()
+			return err
+		},
+		"Entity": func() error {
+			_, err := client.Entity("city")
+			return err
+		},
+		"CreateEntity": func() error {
+			_, err := client.CreateEntity(&wit.Entity{ID: "city"})
+			return err
+		},
+		"CreateEntityValue": func() error {
+			_, err := client.CreateEntityValue("city", &wit.EntityValue{Value: "Paris"})
+			return err
+		},
+		"CreateEntityValueExp": func() error {
+			_, err := client.CreateEntityValueExp("city", "Paris", "Paname")
+			return err
+		},
+	}
+}
+
+func TestMalformedResponses(t *testing.T) {
+	fixtures := map[string]string{
+		"truncated": `{"msg_id":"1","_text":"hel`,
+		"html":      `<html>Bad Gateway</html>`,
+		"wrong":     `{"msg_id":1,"outcomes":"none","id":2,"values":{}}`,
+		"trailing":  `{} {}`,
+		"empty":     ``,
+	}
+	for name, body := range fixtures {
+		server, client := wittest.NewTestServer(t)
+		answerAll(server, body)
+		for call, fn := range decodingCalls(client) {
+			err := fn()
+			var parseError *wit.ParseError
+			if !errors.As(err, &parseError) {
+				t.Errorf("%s with %s body: expected a *wit.ParseError, got %v", call, name, err)
+				continue
+			}
+			if string(parseError.Body) != body {
+				t.Errorf("%s with %s body: unexpected body %q", call, name, parseError.Body)
+			}
+		}
+	}
+}
+
+func TestMalformedListResponses(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	answerAll(server, `[{"id":`)
+	var parseError *wit.ParseError
+	if _, err := client.Intents(); !errors.As(err, &parseError) {
+		t.Errorf("Intents: expected a *wit.ParseError, got %v", err)
+	}
+	if _, err := client.ListEntities(nil).Collect(context.Background()); !errors.As(err, &parseError) {
+		t.Errorf("ListEntities: expected a *wit.ParseError, got %v", err)
+	}
+	if _, err := client.ListIntents(nil).Collect(context.Background()); !errors.As(err, &parseError) {
+		t.Errorf("ListIntents: expected a *wit.ParseError, got %v", err)
+	}
+}
+
+func TestStrictResponses(t *testing.T) {
+	body := `{"msg_id":"1","_text":"hello","outcomes":[{"intent":"greeting","confidence":0.9,"entities":{},"_new":true}]}`
+	server, client := wittest.NewTestServer(t)
+	answerAll(server, body)
+	message, err := client.Message(&wit.MessageRequest{Query: "hello"})
+	if err != nil {
+		t.Fatalf("Lenient decoding failed: %v", err)
+	}
+	if message.Outcomes[0].Intent != "greeting" {
+		t.Errorf("Unexpected intent %q", message.Outcomes[0].Intent)
+	}
+
+	client.Strict = true
+	_, err = client.Message(&wit.MessageRequest{Query: "hello"})
+	var parseError *wit.ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("Expected a *wit.ParseError, got %v", err)
+	}
+	if err.Error() != `wit: invalid response: json: unknown field "_new"` {
+		t.Errorf("Unexpected error %q", err)
+	}
+}
+
+func TestStrictKnownFields(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.Script("GET", "/message", 200, `{"msg_id":"1","_text":"at 9h","outcomes":[{"_text":"at 9h","intent":"alarm","intent_id":"i","confidence":0.5,
+		"entities":{"datetime":[{"type":"value","value":"2015-01-01T09:00:00.000Z","grain":"hour","start":3,"end":5,"body":"9h"}]}}]}`)
+	server.Script("GET", "/entities/city", 200, `{"builtin":false,"doc":"A city","id":"city","name":"city","values":[{"value":"Paris","expressions":["Paris"]}]}`)
+	server.Script("GET", "/intents", 200, `[{"id":"i","name":"alarm","doc":"","metadata":""}]`)
+	client := server.Client()
+	client.Strict = true
+
+	if _, err := client.Message(&wit.MessageRequest{Query: "at 9h"}); err != nil {
+		t.Errorf("Message: %v", err)
+	}
+	if _, err := client.Entity("city"); err != nil {
+		t.Errorf("Entity: %v", err)
+	}
+	if _, err := client.Intents(); err != nil {
+		t.Errorf("Intents: %v", err)
+	}
+}
+
+func TestTruncatedResponse(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.Intercept("GET", "/message", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`{"msg_id":"1"`))
+	})
+	client := server.Client()
+	recorder := &requestRecorder{}
+	client.Metrics = recorder
+
+	_, err := client.Message(&wit.MessageRequest{Query: "hello"})
+	var parseError *wit.ParseError
+	if !errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &parseError) {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+	if len(recorder.observations) != 1 || recorder.observations[0].ErrorClass != wit.ErrorClassNetwork {
+		t.Errorf("Unexpected observations %+v", recorder.observations)
+	}
+}
+
+// Keeps the requests reported to Metrics
+type requestRecorder struct {
+	observations []*wit.RequestObservation
+}
+
+func (recorder *requestRecorder) ObserveRequest(observation *wit.RequestObservation) {
+	recorder.observations = append(recorder.observations, observation)
+}
+
+func (recorder *requestRecorder) ObserveMessage(message *wit.Message) {}
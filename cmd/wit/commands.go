@@ -0,0 +1,152 @@
+// Copyright (c) 2014 Jason Goecke
+// commands.go
+
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// wit message [-n count] [-timezone tz] [-locale locale] [-msg-id id] <text>
+func messageCommand(c *cli, args []string) error {
+	flags := flag.NewFlagSet("message", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	n := flags.Int("n", 0, "number of outcomes to return")
+	timezone := flags.String("timezone", "", "context timezone")
+	locale := flags.String("locale", "", "context locale")
+	msgID := flags.String("msg-id", "", "message id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+
+	request := &wit.MessageRequest{Query: strings.Join(flags.Args(), " "), N: *n, MsgID: *msgID}
+	if *timezone != "" || *locale != "" {
+		request.Context = &wit.Context{Timezone: *timezone, Locale: *locale}
+	}
+	message, err := c.client.Message(request)
+	if err != nil {
+		return err
+	}
+	return c.print(message)
+}
+
+// wit speech [-type content-type] <file>
+func speechCommand(c *cli, args []string) error {
+	flags := flag.NewFlagSet("speech", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	contentType := flags.String("type", "audio/wav", "audio content type")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	message, err := c.client.AudioMessage(&wit.MessageRequest{File: flags.Arg(0), ContentType: *contentType})
+	if err != nil {
+		return err
+	}
+	return c.print(message)
+}
+
+// wit entities list|get|create|delete
+func entitiesCommand(c *cli, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		entities, err := c.client.Entities()
+		if err != nil {
+			return err
+		}
+		return c.print(entities)
+	case args[0] == "get" && len(args) == 2:
+		entity, err := c.client.Entity(args[1])
+		if err != nil {
+			return err
+		}
+		return c.print(entity)
+	case args[0] == "create":
+		flags := flag.NewFlagSet("create", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		doc := flags.String("doc", "", "entity documentation")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errUsage
+		}
+		entity, err := c.client.CreateEntity(&wit.Entity{ID: flags.Arg(0), Doc: *doc})
+		if err != nil {
+			return err
+		}
+		return c.print(entity)
+	case args[0] == "delete" && len(args) == 2:
+		if err := c.client.DeleteEntity(args[1]); err != nil {
+			return err
+		}
+		return c.print(map[string]string{"deleted": args[1]})
+	}
+	return errUsage
+}
+
+// wit values add|rm
+func valuesCommand(c *cli, args []string) error {
+	switch {
+	case len(args) >= 3 && args[0] == "add":
+		entity, err := c.client.CreateEntityValue(args[1], &wit.EntityValue{Value: args[2], Expressions: args[3:]})
+		if err != nil {
+			return err
+		}
+		return c.print(entity)
+	case len(args) == 3 && args[0] == "rm":
+		deletion, err := c.client.DeleteEntityValue(args[1], args[2])
+		if err != nil {
+			return err
+		}
+		return c.print(deletion)
+	}
+	return errUsage
+}
+
+// wit expressions add|rm
+func expressionsCommand(c *cli, args []string) error {
+	if len(args) != 4 {
+		return errUsage
+	}
+	switch args[0] {
+	case "add":
+		entity, err := c.client.CreateEntityValueExp(args[1], args[2], args[3])
+		if err != nil {
+			return err
+		}
+		return c.print(entity)
+	case "rm":
+		deletion, err := c.client.DeleteEntityValueExp(args[1], args[2], args[3])
+		if err != nil {
+			return err
+		}
+		return c.print(deletion)
+	}
+	return errUsage
+}
+
+// wit intents list
+func intentsCommand(c *cli, args []string) error {
+	if len(args) != 1 || args[0] != "list" {
+		return errUsage
+	}
+	intents, err := c.client.Intents()
+	if err != nil {
+		return err
+	}
+	return c.print(intents)
+}
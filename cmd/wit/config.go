@@ -0,0 +1,97 @@
+// Copyright (c) 2014 Jason Goecke
+// config.go
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Represents a named app in the config file
+type profile struct {
+	Token   string
+	APIBase string
+}
+
+// Picks the profile to use. An explicit or $WIT_PROFILE profile is read from
+// the config file, otherwise $WIT_ACCESS_TOKEN wins over the default profile.
+//
+//		profile, err := resolveProfile("", "weather", os.Getenv)
+func resolveProfile(path string, name string, getenv func(string) string) (profile, error) {
+	if name == "" {
+		name = getenv("WIT_PROFILE")
+	}
+	if name == "" && getenv("WIT_ACCESS_TOKEN") != "" {
+		return profile{Token: getenv("WIT_ACCESS_TOKEN")}, nil
+	}
+	if name == "" {
+		name = "default"
+	}
+	if path == "" {
+		path = configPath(getenv)
+	}
+
+	profiles, err := loadProfiles(path)
+	if err != nil && !os.IsNotExist(err) {
+		return profile{}, err
+	}
+	selected, found := profiles[name]
+	if !found || selected.Token == "" {
+		return profile{}, fmt.Errorf("no access token: set WIT_ACCESS_TOKEN or add a token to the [%s] profile in %s", name, path)
+	}
+	return selected, nil
+}
+
+// Returns $WIT_CONFIG or ~/.wit/config
+func configPath(getenv func(string) string) string {
+	if path := getenv("WIT_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(getenv("HOME"), ".wit", "config")
+}
+
+// Parses an INI style config file of [profile] sections with token and
+// api_base keys
+//
+//		profiles, err := loadProfiles("/home/me/.wit/config")
+func loadProfiles(path string) (map[string]profile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles := map[string]profile{}
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			profiles[section] = profile{}
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || section == "" {
+			return nil, fmt.Errorf("%s:%d: expected a [profile] or key = value", path, number)
+		}
+		current := profiles[section]
+		switch strings.TrimSpace(key) {
+		case "token":
+			current.Token = strings.TrimSpace(value)
+		case "api_base":
+			current.APIBase = strings.TrimSpace(value)
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown key %q", path, number, strings.TrimSpace(key))
+		}
+		profiles[section] = current
+	}
+	return profiles, scanner.Err()
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2014 Jason Goecke
+// config_test.go
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	config := `
+# apps
+[default]
+token = default-token
+
+[weather]
+token = weather-token
+api_base = http://localhost:8080
+`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	selected, err := resolveProfile(path, "", getenv)
+	if err != nil || selected.Token != "default-token" {
+		t.Errorf("Expected the default profile, got %+v %v", selected, err)
+	}
+
+	env["WIT_ACCESS_TOKEN"] = "env-token"
+	selected, _ = resolveProfile(path, "", getenv)
+	if selected.Token != "env-token" {
+		t.Errorf("not equal %s != %s", "env-token", selected.Token)
+	}
+
+	selected, _ = resolveProfile(path, "weather", getenv)
+	if selected.Token != "weather-token" || selected.APIBase != "http://localhost:8080" {
+		t.Errorf("Expected the weather profile, got %+v", selected)
+	}
+
+	env["WIT_PROFILE"] = "weather"
+	selected, _ = resolveProfile(path, "", getenv)
+	if selected.Token != "weather-token" {
+		t.Errorf("not equal %s != %s", "weather-token", selected.Token)
+	}
+
+	if _, err = resolveProfile(path, "missing", getenv); err == nil {
+		t.Error("A missing profile should fail")
+	}
+	if _, err = resolveProfile(filepath.Join(t.TempDir(), "none"), "default", getenv); err == nil {
+		t.Error("A missing config file without a token should fail")
+	}
+}
+
+func TestLoadProfilesErrors(t *testing.T) {
+	invalid := []string{
+		"token = orphan\n",
+		"[default]\ntoken\n",
+		"[default]\nsecret = x\n",
+	}
+	for _, config := range invalid {
+		path := filepath.Join(t.TempDir(), "config")
+		if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadProfiles(path); err == nil {
+			t.Errorf("%q should have failed to parse", config)
+		}
+	}
+}
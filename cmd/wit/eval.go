@@ -0,0 +1,136 @@
+// Copyright (c) 2014 Jason Goecke
+// eval.go
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jsgoecke/go-wit/eval"
+)
+
+// wit eval [-concurrency n] [-previous report.json] [-json report.json] [-junit report.xml] <dataset>
+func evalCommand(c *cli, args []string) error {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	concurrency := flags.Int("concurrency", 4, "requests to send at once")
+	previousPath := flags.String("previous", "", "report JSON of a previous run to diff against")
+	jsonPath := flags.String("json", "", "write the report as JSON to this file")
+	junitPath := flags.String("junit", "", "write the results as JUnit XML to this file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	examples, err := eval.LoadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var previous *eval.Report
+	if *previousPath != "" {
+		previous, err = eval.LoadReport(*previousPath)
+		if err != nil {
+			return err
+		}
+	}
+	report, err := eval.Run(context.Background(), c.client, examples, *concurrency)
+	if err != nil {
+		return err
+	}
+
+	if *jsonPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(*jsonPath, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	if *junitPath != "" {
+		file, err := os.Create(*junitPath)
+		if err != nil {
+			return err
+		}
+		err = eval.WriteJUnit(file, report)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	var diff *eval.Diff
+	if previous != nil {
+		diff = eval.Compare(previous, report)
+	}
+	if c.format != "table" {
+		return c.print(struct {
+			Report *eval.Report `json:"report"`
+			Diff   *eval.Diff   `json:"diff,omitempty"`
+		}{report, diff})
+	}
+	return writeEvalSummary(c.out, report, diff)
+}
+
+// Writes the metrics, confusion matrix and calibration of a run as tables
+func writeEvalSummary(out io.Writer, report *eval.Report, diff *eval.Diff) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "examples %d, correct %d, errors %d, accuracy %.3f\n\n", report.Total, report.Correct, report.Errors, report.Accuracy)
+
+	fmt.Fprintln(w, "INTENT\tSUPPORT\tPRECISION\tRECALL\tF1")
+	for _, label := range report.Labels {
+		metrics := report.Intents[label]
+		fmt.Fprintf(w, "%s\t%d\t%.3f\t%.3f\t%.3f\n", label, metrics.Support, metrics.Precision, metrics.Recall, metrics.F1)
+	}
+
+	fmt.Fprintf(w, "\nEXPECTED \\ PREDICTED\t%s\n", strings.Join(report.Labels, "\t"))
+	for _, expected := range report.Labels {
+		row := []string{expected}
+		for _, predicted := range report.Labels {
+			row = append(row, fmt.Sprint(report.Confusion[expected][predicted]))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	entities := report.Entities
+	fmt.Fprintf(w, "\nentities found %d/%d, spans %d/%d (%.3f), values %d/%d (%.3f), spurious %d\n",
+		entities.Found, entities.Expected, entities.SpanCorrect, entities.SpanTotal, entities.SpanAccuracy,
+		entities.ValueCorrect, entities.ValueTotal, entities.ValueAccuracy, entities.Spurious)
+
+	fmt.Fprintln(w, "\nCONFIDENCE\tCOUNT\tMEAN\tACCURACY")
+	for _, bin := range report.Calibration {
+		fmt.Fprintf(w, "%.1f-%.1f\t%d\t%.3f\t%.3f\n", bin.Lower, bin.Upper, bin.Count, bin.MeanConfidence, bin.Accuracy)
+	}
+	fmt.Fprintf(w, "expected calibration error %.3f\n", report.ExpectedCalibrationError)
+
+	if diff != nil {
+		fmt.Fprintf(w, "\naccuracy %+.3f since previous run\n", diff.AccuracyDelta)
+		labels := make([]string, 0, len(diff.F1Delta))
+		for label := range diff.F1Delta {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			if diff.F1Delta[label] != 0 {
+				fmt.Fprintf(w, "F1 %s %+.3f\n", label, diff.F1Delta[label])
+			}
+		}
+		for _, text := range diff.Regressed {
+			fmt.Fprintf(w, "regressed: %s\n", text)
+		}
+		for _, text := range diff.Fixed {
+			fmt.Fprintf(w, "fixed: %s\n", text)
+		}
+	}
+	return w.Flush()
+}
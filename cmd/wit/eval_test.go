@@ -0,0 +1,63 @@
+// Copyright (c) 2014 Jason Goecke
+// eval_test.go
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func TestEvalCommand(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting", Confidence: 0.93}}})
+	server.AddMessage("bye", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting", Confidence: 0.61}}})
+
+	dir := t.TempDir()
+	dataset := filepath.Join(dir, "dataset.csv")
+	if err := os.WriteFile(dataset, []byte("text,intent\nhello,greeting\nbye,goodbye\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first := filepath.Join(dir, "first.json")
+	junit := filepath.Join(dir, "junit.xml")
+	out, err := runAgainst(t, server, "eval", "-concurrency", "2", "-json", first, "-junit", junit, dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"examples 2, correct 1, errors 0, accuracy 0.500",
+		"goodbye   1        0.000      0.000   0.000",
+		"EXPECTED \\ PREDICTED  goodbye  greeting\ngoodbye               0        1\ngreeting              0        1\n",
+		"0.9-1.0     1      0.930  1.000",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out)
+		}
+	}
+	if data, err := os.ReadFile(junit); err != nil || !strings.Contains(string(data), `failures="1"`) {
+		t.Errorf("JUnit report not written properly: %s %v", data, err)
+	}
+
+	server.AddMessage("bye", &wit.Message{Outcomes: []wit.Outcome{{Intent: "goodbye", Confidence: 0.88}}})
+	out, err = runAgainst(t, server, "eval", "-previous", first, dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "accuracy +0.500 since previous run") || !strings.Contains(out, "fixed: bye") {
+		t.Errorf("Diff not shown properly:\n%s", out)
+	}
+
+	out, err = runAgainst(t, server, "-o", "json", "eval", "-previous", first, dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"fixed": [`) || !strings.Contains(out, `"accuracy": 1`) {
+		t.Errorf("Unexpected json output:\n%s", out)
+	}
+}
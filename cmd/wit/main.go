@@ -0,0 +1,138 @@
+// Copyright (c) 2014 Jason Goecke
+// main.go
+
+// Command wit is a command line tool for poking a Wit app through go-wit.
+//
+//		wit message "what's the weather in Paris?"
+//		wit -o yaml speech ./audio_sample/helloWorld.wav
+//		wit -profile weather entities get favorite_city
+//
+// The access token is read from WIT_ACCESS_TOKEN, or from a named profile in
+// the config file ($WIT_CONFIG, defaulting to ~/.wit/config):
+//
+//		[default]
+//		token = <ACCESS-TOKEN>
+//
+//		[weather]
+//		token = <ANOTHER-ACCESS-TOKEN>
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+const usage = `usage: wit [-o table|json|yaml] [-profile name] [-config path] <command> [arguments]
+
+commands:
+  message [-n count] [-timezone tz] [-locale locale] [-msg-id id] <text>
+  speech [-type content-type] <file>
+  entities list
+  entities get <entity>
+  entities create [-doc doc] <entity>
+  entities delete <entity>
+  values add <entity> <value> [expression...]
+  values rm <entity> <value>
+  expressions add <entity> <value> <expression>
+  expressions rm <entity> <value> <expression>
+  intents list
+  repl [-color]
+  eval [-concurrency n] [-previous report.json] [-json report.json] [-junit report.xml] <dataset>
+  serve [-addr host:port] [-keys file] [-rps n] [-burst n] [-cache-size n] [-cache-ttl duration] [-max-audio bytes]
+`
+
+// Holds the state shared by every command
+type cli struct {
+	client *wit.Client
+	format string
+	in     io.Reader
+	out    io.Writer
+}
+
+// A command receives the arguments following its name
+type command func(c *cli, args []string) error
+
+var commands = map[string]command{
+	"message":     messageCommand,
+	"speech":      speechCommand,
+	"entities":    entitiesCommand,
+	"values":      valuesCommand,
+	"expressions": expressionsCommand,
+	"intents":     intentsCommand,
+	"repl":        replCommand,
+	"eval":        evalCommand,
+	"serve":       serveCommand,
+}
+
+var errUsage = errors.New("invalid usage, see wit -h")
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wit:", err)
+		os.Exit(1)
+	}
+}
+
+// Parses the global flags, loads the profile and dispatches to the command
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer, getenv func(string) string) error {
+	flags := flag.NewFlagSet("wit", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	// The usage is printed below, to stdout when it was asked for
+	flags.Usage = func() {}
+	format := flags.String("o", "table", "output format: table, json or yaml")
+	profileName := flags.String("profile", "", "config file profile, defaults to $WIT_PROFILE or default")
+	configPath := flags.String("config", "", "config file, defaults to $WIT_CONFIG or ~/.wit/config")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			fmt.Fprint(stdout, usage)
+			return nil
+		}
+		fmt.Fprint(stderr, usage)
+		return err
+	}
+	if *format != "table" && *format != "json" && *format != "yaml" {
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+	cmd, found := commands[flags.Arg(0)]
+	if !found {
+		return fmt.Errorf("unknown command %q", flags.Arg(0))
+	}
+	if helpRequested(flags.Args()[1:]) {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+
+	profile, err := resolveProfile(*configPath, *profileName, getenv)
+	if err != nil {
+		return err
+	}
+	client := wit.NewClient(profile.Token)
+	if profile.APIBase != "" {
+		client.APIBase = profile.APIBase
+	}
+	return cmd(&cli{client: client, format: *format, in: stdin, out: stdout}, flags.Args()[1:])
+}
+
+// Reports whether a command's arguments ask for help, before the profile is
+// resolved so that help works without an access token
+func helpRequested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "-help", "--h", "--help":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2014 Jason Goecke
+// main_test.go
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+// Runs the tool against a fake server through a config profile
+func runAgainst(t *testing.T, server *wittest.Server, args ...string) (string, error) {
+	t.Helper()
+	return runWithInput(t, server, "", args...)
+}
+
+// Runs the tool against a fake server with the given standard input
+func runWithInput(t *testing.T, server *wittest.Server, input string, args ...string) (string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	config := "[test]\ntoken = secret\napi_base = " + server.URL + "\n"
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	getenv := func(string) string { return "" }
+	err := run(append([]string{"-config", path, "-profile", "test"}, args...), strings.NewReader(input), &stdout, &stderr, getenv)
+	return stdout.String(), err
+}
+
+func TestMessageCommand(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	value := interface{}("Paris")
+	server.AddMessage("weather in Paris", &wit.Message{Outcomes: []wit.Outcome{{
+		Intent:     "weather",
+		Confidence: 0.87,
+		Entities:   map[string][]wit.MessageEntity{"location": {{Value: &value}}},
+	}}})
+
+	out, err := runAgainst(t, server, "message", "-n", "2", "-timezone", "Europe/Paris", "weather", "in", "Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "weather in Paris  weather  0.870       location=Paris") {
+		t.Errorf("Unexpected table output:\n%s", out)
+	}
+	server.AssertQuery(t, "n", "2")
+	server.AssertQuery(t, "context", `{"timezone":"Europe/Paris"}`)
+	request := server.AssertRequested(t, "GET", "/message")
+	if request.Header.Get("Authorization") != "Bearer secret" {
+		t.Error("Profile token was not used")
+	}
+
+	out, err = runAgainst(t, server, "-o", "json", "message", "weather in Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"intent": "weather"`) {
+		t.Errorf("Unexpected json output:\n%s", out)
+	}
+}
+
+func TestSpeechCommand(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.SetSpeech(&wit.Message{Text: "hello world", Outcomes: []wit.Outcome{{Intent: "hello"}}})
+
+	out, err := runAgainst(t, server, "-o", "yaml", "speech", "-type", "audio/wav", "../../audio_sample/helloWorld.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "_text: hello world\n") || !strings.Contains(out, "  - _text: \"\"\n") {
+		t.Errorf("Unexpected yaml output:\n%s", out)
+	}
+}
+
+func TestEntityCommands(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+
+	steps := [][]string{
+		{"entities", "create", "-doc", "A city that I like", "favorite_city"},
+		{"values", "add", "favorite_city", "Paris", "City of Light"},
+		{"expressions", "add", "favorite_city", "Paris", "Capital of France"},
+		{"expressions", "rm", "favorite_city", "Paris", "City of Light"},
+	}
+	for _, step := range steps {
+		if _, err := runAgainst(t, server, step...); err != nil {
+			t.Fatalf("%v: %s", step, err)
+		}
+	}
+
+	out, err := runAgainst(t, server, "entities", "get", "favorite_city")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "favorite_city  Paris  Capital of France") {
+		t.Errorf("Unexpected table output:\n%s", out)
+	}
+	out, err = runAgainst(t, server, "entities", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "ID\nfavorite_city\n" {
+		t.Errorf("Unexpected table output:\n%s", out)
+	}
+
+	if _, err = runAgainst(t, server, "values", "rm", "favorite_city", "Paris"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = runAgainst(t, server, "entities", "delete", "favorite_city"); err != nil {
+		t.Fatal(err)
+	}
+	server.AssertRequested(t, "DELETE", "/entities/favorite_city")
+}
+
+func TestIntentsCommand(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddIntent("1234", "recover_password", "Recover password")
+
+	out, err := runAgainst(t, server, "intents", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "1234  recover_password  Recover password") {
+		t.Errorf("Unexpected table output:\n%s", out)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+
+	invalid := [][]string{
+		{"bogus"},
+		{"entities"},
+		{"entities", "get"},
+		{"values", "add", "favorite_city"},
+		{"expressions", "add", "favorite_city", "Paris"},
+		{"intents"},
+		{"message"},
+		{"-o", "xml", "intents", "list"},
+	}
+	for _, args := range invalid {
+		if _, err := runAgainst(t, server, args...); err == nil {
+			t.Errorf("%v should have failed", args)
+		}
+	}
+	server.AssertRequestCount(t, 0)
+}
+
+func TestHelp(t *testing.T) {
+	getenv := func(string) string { return "" }
+	for _, args := range [][]string{{"-h"}, {"-help"}, {"message", "-h"}, {"entities", "create", "-help"}, {"serve", "--help"}} {
+		var stdout, stderr bytes.Buffer
+		config := filepath.Join(t.TempDir(), "missing")
+		if err := run(append([]string{"-config", config}, args...), strings.NewReader(""), &stdout, &stderr, getenv); err != nil {
+			t.Errorf("%v should print the usage without failing, got %v", args, err)
+		}
+		if !strings.HasPrefix(stdout.String(), "usage: wit") {
+			t.Errorf("%v did not print the usage to stdout: %q", args, stdout.String())
+		}
+	}
+}
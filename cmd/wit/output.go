@@ -0,0 +1,228 @@
+// Copyright (c) 2014 Jason Goecke
+// output.go
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// Writes a result in the selected output format
+//
+//		err := c.print(message)
+func (c *cli) print(v interface{}) error {
+	switch c.format {
+	case "json":
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.out, "%s\n", data)
+		return err
+	case "yaml":
+		return writeYAML(c.out, v)
+	}
+	return writeTable(c.out, v)
+}
+
+// Renders the results the tool knows how to tabulate, falling back to JSON
+func writeTable(out io.Writer, v interface{}) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	switch v := v.(type) {
+	case *wit.Message:
+		fmt.Fprintln(w, "TEXT\tINTENT\tCONFIDENCE\tENTITIES")
+		for _, outcome := range v.Outcomes {
+			text := outcome.Text
+			if text == "" {
+				text = v.Text
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.3f\t%s\n", text, outcome.Intent, outcome.Confidence, formatEntities(outcome.Entities))
+		}
+	case *wit.Entities:
+		fmt.Fprintln(w, "ID")
+		for _, id := range *v {
+			fmt.Fprintln(w, id)
+		}
+	case *wit.Entity:
+		fmt.Fprintln(w, "ID\tVALUE\tEXPRESSIONS")
+		if len(v.Values) == 0 {
+			fmt.Fprintf(w, "%s\t\t\n", v.ID)
+		}
+		for _, value := range v.Values {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.ID, value.Value, strings.Join(value.Expressions, ", "))
+		}
+	case *wit.Intents:
+		fmt.Fprintln(w, "ID\tNAME\tDOC")
+		for _, intent := range *v {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", intent.ID, intent.Name, intent.Doc)
+		}
+	default:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", data)
+	}
+	return w.Flush()
+}
+
+// Formats an outcome's entities as name=value pairs sorted by name
+func formatEntities(entities map[string][]wit.MessageEntity) string {
+	var pairs []string
+	for name, values := range entities {
+		for _, entity := range values {
+			value := ""
+			if entity.Value != nil {
+				value = fmt.Sprint(*entity.Value)
+			} else if entity.From != nil || entity.To != nil {
+				value = formatInterval(entity.From, entity.To)
+			}
+			pairs = append(pairs, name+"="+value)
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+func formatInterval(from *wit.DatetimeIntervalEnd, to *wit.DatetimeIntervalEnd) string {
+	interval := ""
+	if from != nil {
+		interval = from.Value
+	}
+	interval += "/"
+	if to != nil {
+		interval += to.Value
+	}
+	return interval
+}
+
+// Writes v as YAML by way of its JSON encoding, so field names match the
+// json output format
+//
+//		err := writeYAML(os.Stdout, message)
+func writeYAML(out io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err = decoder.Decode(&generic); err != nil {
+		return err
+	}
+
+	var buf strings.Builder
+	switch generic.(type) {
+	case map[string]interface{}, []interface{}:
+		if isEmpty(generic) {
+			writeYAMLValue(&buf, generic, "")
+			_, err = io.WriteString(out, strings.TrimPrefix(buf.String(), " "))
+			return err
+		}
+		writeYAMLNode(&buf, generic, "")
+	default:
+		buf.WriteString(yamlScalar(generic) + "\n")
+	}
+	_, err = io.WriteString(out, buf.String())
+	return err
+}
+
+// Writes the entries of a non-empty mapping or sequence at the given indent
+func writeYAMLNode(buf *strings.Builder, v interface{}, indent string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			buf.WriteString(indent + yamlScalar(key) + ":")
+			writeYAMLValue(buf, v[key], indent)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if mapping, ok := item.(map[string]interface{}); ok && len(mapping) > 0 {
+				// Start the mapping on the same line as its dash
+				var nested strings.Builder
+				writeYAMLNode(&nested, mapping, indent+"  ")
+				buf.WriteString(indent + "- " + nested.String()[len(indent)+2:])
+				continue
+			}
+			buf.WriteString(indent + "-")
+			writeYAMLValue(buf, item, indent)
+		}
+	}
+}
+
+// Writes the value following a "key:" or "-", nesting collections one level deeper
+func writeYAMLValue(buf *strings.Builder, v interface{}, indent string) {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		if isEmpty(v) {
+			if _, ok := v.([]interface{}); ok {
+				buf.WriteString(" []\n")
+			} else {
+				buf.WriteString(" {}\n")
+			}
+			return
+		}
+		buf.WriteString("\n")
+		writeYAMLNode(buf, v, indent+"  ")
+	default:
+		buf.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// Formats a scalar, double quoting strings YAML would otherwise misread
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if needsQuotes(v) {
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+func needsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\n\t\"\\") {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'%@`") || strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2014 Jason Goecke
+// output_test.go
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteYAML(t *testing.T) {
+	v := map[string]interface{}{
+		"name":    "favorite_city",
+		"builtin": false,
+		"count":   3,
+		"doc":     "true",
+		"empty":   []string{},
+		"values": []map[string]interface{}{
+			{"value": "Paris", "expressions": []string{"Paris", "City: Light"}},
+		},
+		"nested": map[string]interface{}{"lat": 37.47},
+	}
+	expected := `builtin: false
+count: 3
+doc: "true"
+empty: []
+name: favorite_city
+nested:
+  lat: 37.47
+values:
+  - expressions:
+      - Paris
+      - "City: Light"
+    value: Paris
+`
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("not equal\n%s\n!=\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	writeYAML(&buf, []string{})
+	if buf.String() != "[]\n" {
+		t.Errorf("not equal %q != %q", "[]\n", buf.String())
+	}
+	buf.Reset()
+	writeYAML(&buf, "-1")
+	if buf.String() != "\"-1\"\n" {
+		t.Errorf("not equal %q != %q", "\"-1\"\n", buf.String())
+	}
+}
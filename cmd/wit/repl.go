@@ -0,0 +1,350 @@
+// Copyright (c) 2014 Jason Goecke
+// repl.go
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+const replHelp = `type a sentence to see how Wit understands it, or:
+  t              correct and train the last sentence's entity
+  y              accept the top outcome, training its entities as they are
+  !!, !<n>       re-run the last or nth sentence
+  :history       list the sentences run so far
+  :tz <zone>     set the context timezone
+  :locale <loc>  set the context locale
+  :context       show the session context
+  :reset         clear the session context
+  :help          show this help
+  :quit          leave
+`
+
+const (
+	underline = "\x1b[4m"
+	reset     = "\x1b[0m"
+)
+
+// Holds the state of an interactive session
+type repl struct {
+	c       *cli
+	input   *bufio.Scanner
+	color   bool
+	context wit.Context
+	history []string
+	// Most recent result per sentence, along with the training count at the time
+	results   map[string]replResult
+	last      *wit.Message
+	lastText  string
+	trainings int
+}
+
+type replResult struct {
+	message   *wit.Message
+	trainings int
+}
+
+// wit repl [-color]
+func replCommand(c *cli, args []string) error {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	color := flags.Bool("color", isTerminal(c.out), "underline entity spans with ANSI escapes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	r := &repl{c: c, input: bufio.NewScanner(c.in), color: *color, results: map[string]replResult{}}
+	fmt.Fprintln(c.out, "wit repl, :help for commands")
+	for {
+		line, ok := r.prompt("> ")
+		if !ok {
+			return r.input.Err()
+		}
+		if line == ":quit" || line == ":q" {
+			return nil
+		}
+		if err := r.handle(line); err != nil {
+			fmt.Fprintln(c.out, "error:", err)
+		}
+	}
+}
+
+// Writes the prompt and reads the next trimmed line
+func (r *repl) prompt(prompt string) (string, bool) {
+	fmt.Fprint(r.c.out, prompt)
+	if !r.input.Scan() {
+		fmt.Fprintln(r.c.out)
+		return "", false
+	}
+	return strings.TrimSpace(r.input.Text()), true
+}
+
+func (r *repl) handle(line string) error {
+	command, argument, _ := strings.Cut(line, " ")
+	argument = strings.TrimSpace(argument)
+	switch {
+	case line == "":
+		return nil
+	case line == "t":
+		return r.train()
+	case line == "y":
+		return r.accept()
+	case line == "!!":
+		if len(r.history) == 0 {
+			return fmt.Errorf("no history")
+		}
+		return r.run(r.history[len(r.history)-1], false)
+	case strings.HasPrefix(line, "!"):
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 1 || n > len(r.history) {
+			return fmt.Errorf("no history entry %s", line[1:])
+		}
+		return r.run(r.history[n-1], false)
+	case command == ":history":
+		for i, text := range r.history {
+			fmt.Fprintf(r.c.out, "%4d  %s\n", i+1, text)
+		}
+	case command == ":tz":
+		r.context.Timezone = argument
+	case command == ":locale":
+		r.context.Locale = argument
+	case command == ":context":
+		fmt.Fprintf(r.c.out, "timezone=%s locale=%s\n", r.context.Timezone, r.context.Locale)
+	case command == ":reset":
+		r.context = wit.Context{}
+	case command == ":help":
+		fmt.Fprint(r.c.out, replHelp)
+	case strings.HasPrefix(line, ":"):
+		return fmt.Errorf("unknown command %s, :help for commands", command)
+	default:
+		return r.run(line, true)
+	}
+	return nil
+}
+
+// Sends a sentence to Wit, showing the result and how it changed since the
+// sentence was last run. Re-runs are not added to the history again.
+func (r *repl) run(text string, remember bool) error {
+	request := &wit.MessageRequest{Query: text}
+	if r.context != (wit.Context{}) {
+		context := r.context
+		request.Context = &context
+	}
+	message, err := r.c.client.Message(request)
+	if err != nil {
+		return err
+	}
+	if remember {
+		r.history = append(r.history, text)
+	}
+	r.last, r.lastText = message, text
+
+	r.show(text, message)
+	if previous, found := r.results[text]; found {
+		label := "since last run"
+		if previous.trainings != r.trainings {
+			label = "since training"
+		}
+		r.diff(label, previous.message, message)
+	}
+	r.results[text] = replResult{message, r.trainings}
+	return nil
+}
+
+func (r *repl) show(text string, message *wit.Message) {
+	if len(message.Outcomes) == 0 {
+		fmt.Fprintln(r.c.out, "no outcomes")
+		return
+	}
+	for i, outcome := range message.Outcomes {
+		fmt.Fprintf(r.c.out, "%d. %s (%.3f)\n", i+1, outcome.Intent, outcome.Confidence)
+		spans := outcome.Spans(text)
+		fmt.Fprintf(r.c.out, "   %s\n", highlight(text, spans, r.color))
+		for _, s := range spans {
+			fmt.Fprintf(r.c.out, "   %s = %s [%d:%d]\n", s.Name, spanValue(s), s.Start, s.End)
+		}
+	}
+}
+
+// Prints the differences in the top outcome between two runs of a sentence
+func (r *repl) diff(label string, before *wit.Message, after *wit.Message) {
+	var beforeOutcome, afterOutcome wit.Outcome
+	if len(before.Outcomes) > 0 {
+		beforeOutcome = before.Outcomes[0]
+	}
+	if len(after.Outcomes) > 0 {
+		afterOutcome = after.Outcomes[0]
+	}
+	var lines []string
+	if beforeOutcome.Intent != afterOutcome.Intent || beforeOutcome.Confidence != afterOutcome.Confidence {
+		lines = append(lines, fmt.Sprintf("~ %s (%.3f) -> %s (%.3f)",
+			beforeOutcome.Intent, beforeOutcome.Confidence, afterOutcome.Intent, afterOutcome.Confidence))
+	}
+	beforePairs := pairSet(beforeOutcome.Entities)
+	afterPairs := pairSet(afterOutcome.Entities)
+	for _, pair := range sortedKeys(afterPairs) {
+		if !beforePairs[pair] {
+			lines = append(lines, "+ "+pair)
+		}
+	}
+	for _, pair := range sortedKeys(beforePairs) {
+		if !afterPairs[pair] {
+			lines = append(lines, "- "+pair)
+		}
+	}
+	if len(lines) == 0 {
+		fmt.Fprintf(r.c.out, "unchanged %s\n", label)
+		return
+	}
+	fmt.Fprintf(r.c.out, "changed %s:\n", label)
+	for _, line := range lines {
+		fmt.Fprintln(r.c.out, "   "+line)
+	}
+}
+
+// Corrects the last sentence by adding an expression to an entity value,
+// defaulting every answer to the first entity found
+func (r *repl) train() error {
+	if r.last == nil {
+		return fmt.Errorf("nothing to train, type a sentence first")
+	}
+	expression := r.lastText
+	var defaultEntity, defaultValue string
+	if len(r.last.Outcomes) > 0 {
+		if spans := r.last.Outcomes[0].Spans(r.lastText); len(spans) > 0 {
+			defaultEntity, defaultValue, expression = spans[0].Name, spanValue(spans[0]), spans[0].Text
+		}
+	}
+	entityID, ok := r.ask("entity", defaultEntity)
+	if !ok {
+		return nil
+	}
+	value, ok := r.ask("value", defaultValue)
+	if !ok {
+		return nil
+	}
+	expression, ok = r.ask("expression", expression)
+	if !ok {
+		return nil
+	}
+	return r.teach(entityID, value, expression)
+}
+
+// Trains every entity of the last sentence's top outcome as Wit found it
+func (r *repl) accept() error {
+	if r.last == nil {
+		return fmt.Errorf("nothing to accept, type a sentence first")
+	}
+	var spans []wit.EntitySpan
+	if len(r.last.Outcomes) > 0 {
+		spans = r.last.Outcomes[0].Spans(r.lastText)
+	}
+	if len(spans) == 0 {
+		return fmt.Errorf("no entities to accept, t to train by hand")
+	}
+	for _, s := range spans {
+		if err := r.teach(s.Name, spanValue(s), s.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds an expression to an entity value, creating the value when missing
+func (r *repl) teach(entityID string, value string, expression string) error {
+	entity, err := r.c.client.Entity(entityID)
+	if err != nil {
+		return err
+	}
+	exists := false
+	for _, entityValue := range entity.Values {
+		if entityValue.Value == value {
+			exists = true
+		}
+	}
+	if exists {
+		_, err = r.c.client.CreateEntityValueExp(entityID, value, expression)
+	} else {
+		_, err = r.c.client.CreateEntityValue(entityID, &wit.EntityValue{Value: value, Expressions: []string{expression}})
+	}
+	if err != nil {
+		return err
+	}
+	r.trainings++
+	fmt.Fprintf(r.c.out, "trained %s: %q -> %s, !! to re-run\n", entityID, expression, value)
+	return nil
+}
+
+// Asks for an answer, returning the default when the answer is left empty
+func (r *repl) ask(question string, defaultAnswer string) (string, bool) {
+	answer, ok := r.prompt(fmt.Sprintf("%s [%s]: ", question, defaultAnswer))
+	if !ok {
+		return "", false
+	}
+	if answer == "" {
+		answer = defaultAnswer
+	}
+	if answer == "" {
+		fmt.Fprintf(r.c.out, "no %s given, not training\n", question)
+		return "", false
+	}
+	return answer, true
+}
+
+// The value of an entity span, or its text when Wit gave no value
+func spanValue(s wit.EntitySpan) string {
+	if s.Entity.Value != nil {
+		return fmt.Sprint(*s.Entity.Value)
+	}
+	return s.Text
+}
+
+// Marks up each span as [text](entity), underlining the text when color is set
+func highlight(text string, spans []wit.EntitySpan, color bool) string {
+	if !color {
+		return wit.Markup(text, spans, nil)
+	}
+	return wit.Markup(text, spans, func(s wit.EntitySpan) string {
+		return "[" + underline + s.Text + reset + "](" + s.Name + ")"
+	})
+}
+
+func pairSet(entities map[string][]wit.MessageEntity) map[string]bool {
+	pairs := map[string]bool{}
+	for name, values := range entities {
+		for _, entity := range values {
+			pairs[formatEntities(map[string][]wit.MessageEntity{name: {entity}})] = true
+		}
+	}
+	return pairs
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := file.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
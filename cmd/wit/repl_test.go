@@ -0,0 +1,141 @@
+// Copyright (c) 2014 Jason Goecke
+// repl_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func TestReplTrainAndDiff(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddEntity(&wit.Entity{ID: "location", Values: []wit.EntityValue{{Value: "Paris", Expressions: []string{"Paris"}}}})
+	start, end := int64(11), int64(24)
+	value := interface{}("Paris")
+	before := `{"_text":"flights to City of Light","outcomes":[{"intent":"book_flight","confidence":0.41,"entities":{}}]}`
+	server.Script("GET", "/message", 200, before)
+	server.AddMessage("flights to City of Light", &wit.Message{Outcomes: []wit.Outcome{{
+		Intent:     "book_flight",
+		Confidence: 0.92,
+		Entities:   map[string][]wit.MessageEntity{"location": {{Value: &value, Start: &start, End: &end}}},
+	}}})
+
+	input := strings.Join([]string{
+		":tz Europe/Paris",
+		"flights to City of Light",
+		"t",
+		"location",
+		"Paris",
+		"City of Light",
+		"!!",
+		":history",
+		":quit",
+	}, "\n")
+	out, err := runWithInput(t, server, input, "repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"1. book_flight (0.410)\n   flights to City of Light\n",
+		`trained location: "City of Light" -> Paris`,
+		"1. book_flight (0.920)\n   flights to [City of Light](location)\n   location = Paris [11:24]\n",
+		"changed since training:\n   ~ book_flight (0.410) -> book_flight (0.920)\n   + location=Paris\n",
+		"   1  flights to City of Light\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, out)
+		}
+	}
+	if strings.Contains(out, "   2  flights to City of Light") {
+		t.Errorf("!! added the sentence to the history again:\n%s", out)
+	}
+	server.AssertQuery(t, "context", `{"timezone":"Europe/Paris"}`)
+	server.AssertRequested(t, "POST", "/entities/location/values/Paris/expressions")
+}
+
+func TestReplNewValueAndErrors(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddEntity(&wit.Entity{ID: "contact"})
+
+	input := strings.Join([]string{"t", "call ali", ":bogus", "!9", "t", "contact", "Alice", "", ""}, "\n")
+	out, err := runWithInput(t, server, input, "repl", "-color=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{
+		"error: nothing to train",
+		"no outcomes",
+		"error: unknown command :bogus",
+		"error: no history entry 9",
+		`trained contact: "call ali" -> Alice`,
+	} {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, out)
+		}
+	}
+	request := server.AssertRequested(t, "POST", "/entities/contact/values")
+	if string(request.Body) != `{"value":"Alice","expressions":["call ali"]}` {
+		t.Errorf("Unexpected training request %s", request.Body)
+	}
+}
+
+func TestReplAccept(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddEntity(&wit.Entity{ID: "location", Values: []wit.EntityValue{{Value: "Paris", Expressions: []string{"Paris"}}}})
+	server.AddEntity(&wit.Entity{ID: "contact"})
+	paris, ali := interface{}("Paris"), interface{}("Alice")
+	parisStart, parisEnd, aliStart, aliEnd := int64(12), int64(25), int64(5), int64(8)
+	server.AddMessage("call ali in City of Light", &wit.Message{Outcomes: []wit.Outcome{{
+		Intent: "call",
+		Entities: map[string][]wit.MessageEntity{
+			"location": {{Value: &paris, Start: &parisStart, End: &parisEnd}},
+			"contact":  {{Value: &ali, Start: &aliStart, End: &aliEnd}},
+		},
+	}}})
+	server.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting"}}})
+
+	input := strings.Join([]string{"y", "call ali in City of Light", "y", "hello", "y"}, "\n")
+	out, err := runWithInput(t, server, input, "repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{
+		"error: nothing to accept",
+		`trained contact: "ali" -> Alice`,
+		`trained location: "City of Light" -> Paris`,
+		"error: no entities to accept",
+	} {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, out)
+		}
+	}
+	server.AssertRequested(t, "POST", "/entities/location/values/Paris/expressions")
+	request := server.AssertRequested(t, "POST", "/entities/contact/values")
+	if string(request.Body) != `{"value":"Alice","expressions":["ali"]}` {
+		t.Errorf("Unexpected training request %s", request.Body)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	spans := []wit.EntitySpan{
+		{Name: "location", Text: "Paris", Start: 11, End: 16},
+		{Name: "city", Text: "Par", Start: 11, End: 14},
+		{Name: "datetime", Text: "tomorrow", Start: 17, End: 25},
+	}
+	text := "flights to Paris tomorrow"
+	if out := highlight(text, spans, false); out != "flights to [Paris](location) [tomorrow](datetime)" {
+		t.Errorf("Unexpected markup %s", out)
+	}
+	if out := highlight(text, spans[:1], true); out != "flights to ["+underline+"Paris"+reset+"](location) tomorrow" {
+		t.Errorf("Unexpected markup %q", out)
+	}
+}
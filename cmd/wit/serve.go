@@ -0,0 +1,111 @@
+// Copyright (c) 2014 Jason Goecke
+// serve.go
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/witserver"
+)
+
+// wit serve [-addr host:port] [-keys file] [-rps n] [-burst n] [-cache-size n] [-cache-ttl duration] [-max-audio bytes]
+func serveCommand(c *cli, args []string) error {
+	server, err := newServer(c, args)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(c.out, "listening on %s\n", server.Addr)
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Builds the HTTP server from the serve flags
+func newServer(c *cli, args []string) (*http.Server, error) {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	keysPath := flags.String("keys", "", "file of \"caller key\" lines; when unset no key is required")
+	rps := flags.Float64("rps", 0, "requests per second allowed per caller")
+	burst := flags.Int("burst", 1, "requests a caller may make at once")
+	cacheSize := flags.Int("cache-size", 0, "messages to cache")
+	cacheTTL := flags.Duration("cache-ttl", 10*time.Minute, "how long cached messages stay fresh")
+	maxAudio := flags.Int64("max-audio", witserver.DefaultMaxAudioBytes, "largest speech upload in bytes")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != 0 {
+		return nil, errUsage
+	}
+
+	opts := &witserver.Options{
+		RequestsPerSecond: *rps,
+		Burst:             *burst,
+		MaxAudioBytes:     *maxAudio,
+		Logger:            slog.New(slog.NewTextHandler(c.out, nil)),
+	}
+	if *keysPath != "" {
+		keys, err := loadKeys(*keysPath)
+		if err != nil {
+			return nil, err
+		}
+		opts.Keys = keys
+	}
+	if *cacheSize > 0 {
+		c.client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(*cacheSize, *cacheTTL), "")
+	}
+	return &http.Server{Addr: *addr, Handler: witserver.New(c.client, opts), ReadHeaderTimeout: 10 * time.Second}, nil
+}
+
+// Reads "caller key" lines into a map of key to caller, skipping blank lines
+// and # comments
+func loadKeys(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	keys := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"caller key\"", path, line)
+		}
+		keys[fields[1]] = fields[0]
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no keys", path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2014 Jason Goecke
+// serve_test.go
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func TestServe(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting", Confidence: 0.9}}})
+	keys := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(keys, []byte("# downstream callers\nbilling key-1\n\nsearch key-2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	c := &cli{client: server.Client(), out: &out}
+	httpServer, err := newServer(c, []string{"-addr", "127.0.0.1:9000", "-keys", keys, "-cache-size", "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if httpServer.Addr != "127.0.0.1:9000" {
+		t.Errorf("addr = %q", httpServer.Addr)
+	}
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("POST", "/v1/message", strings.NewReader(`{"text":"hello"}`))
+		r.Header.Set("X-API-Key", "key-2")
+		w := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"intent":"greeting"`) {
+			t.Fatalf("response = %d: %s", w.Code, w.Body)
+		}
+	}
+	server.AssertRequestCount(t, 1)
+	if !strings.Contains(out.String(), "caller=search") {
+		t.Errorf("request was not logged: %s", out.String())
+	}
+}
+
+func TestLoadKeysErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"malformed": "billing\n",
+		"empty":     "# nobody\n",
+	}
+	for name, contents := range tests {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(contents), 0600)
+		if _, err := loadKeys(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
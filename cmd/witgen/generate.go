@@ -0,0 +1,194 @@
+// Copyright (c) 2014 Jason Goecke
+// generate.go
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// Represents the parts of a Wit app the code is generated from
+type schema struct {
+	intents  []string
+	entities []*wit.Entity
+}
+
+// Go types of the built-in entities whose values are not strings
+var builtinTypes = map[string]string{
+	"datetime":      "time.Time",
+	"duration":      "time.Duration",
+	"number":        "float64",
+	"ordinal":       "float64",
+	"age_of_person": "float64",
+}
+
+// The calls witgen makes, satisfied by *wit.Client
+type schemaAPI interface {
+	Intents() (*wit.Intents, error)
+	Entities() (*wit.Entities, error)
+	Entity(id string) (*wit.Entity, error)
+}
+
+// Reads the intents and every entity with its values
+func fetchSchema(client schemaAPI) (*schema, error) {
+	intents, err := client.Intents()
+	if err != nil {
+		return nil, fmt.Errorf("listing intents: %v", err)
+	}
+	ids, err := client.Entities()
+	if err != nil {
+		return nil, fmt.Errorf("listing entities: %v", err)
+	}
+	s := &schema{}
+	if intents != nil {
+		for _, intent := range *intents {
+			s.intents = append(s.intents, intent.Name)
+		}
+	}
+	if ids != nil {
+		for _, id := range *ids {
+			entity, err := client.Entity(id)
+			if err != nil {
+				return nil, fmt.Errorf("getting entity %s: %v", id, err)
+			}
+			if entity.ID == "" {
+				entity.ID = id
+			}
+			s.entities = append(s.entities, entity)
+		}
+	}
+	sort.Strings(s.intents)
+	sort.Slice(s.entities, func(i, j int) bool { return s.entities[i].ID < s.entities[j].ID })
+	return s, nil
+}
+
+// Renders the schema as a formatted Go file of package pkg
+func generate(s *schema, pkg string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by witgen from the Wit app; DO NOT EDIT.\n\npackage %s\n\n", pkg)
+
+	type field struct {
+		name, goType, tag string
+	}
+	var fields []field
+	usesTime := false
+	names := newNamer()
+	for _, entity := range s.entities {
+		tag := entityName(entity.ID)
+		fieldName := names.unique(identifier(tag, "Entity"))
+		goType := "string"
+		if builtin, found := builtinTypes[tag]; found && isBuiltin(entity) {
+			goType = builtin
+			usesTime = usesTime || strings.HasPrefix(builtin, "time.")
+		} else if len(entity.Values) > 0 && !isBuiltin(entity) {
+			goType = names.unique(identifier(tag, "Entity") + "Value")
+		}
+		fields = append(fields, field{fieldName, goType, tag})
+	}
+	if usesTime {
+		b.WriteString("import \"time\"\n\n")
+	}
+
+	if len(s.intents) > 0 {
+		b.WriteString("// Intent names of the Wit app\nconst (\n")
+		for _, intent := range s.intents {
+			fmt.Fprintf(&b, "\t%s = %s\n", names.unique("Intent"+identifier(intent, "")), strconv.Quote(intent))
+		}
+		b.WriteString(")\n\n")
+	}
+
+	for i, entity := range s.entities {
+		goType := fields[i].goType
+		if isBuiltin(entity) || len(entity.Values) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "// %s is a value of the %s entity\ntype %s string\n\n", goType, entity.ID, goType)
+		fmt.Fprintf(&b, "// Values of the %s entity\nconst (\n", entity.ID)
+		values := append([]wit.EntityValue(nil), entity.Values...)
+		sort.Slice(values, func(i, j int) bool { return values[i].Value < values[j].Value })
+		for _, value := range values {
+			name := names.unique(strings.TrimSuffix(goType, "Value") + identifier(value.Value, "Value"))
+			fmt.Fprintf(&b, "\t%s %s = %s\n", name, goType, strconv.Quote(value.Value))
+		}
+		b.WriteString(")\n\n")
+	}
+
+	b.WriteString("// Entities holds the entities of an outcome, filled by wit.Outcome.Decode.\n")
+	b.WriteString("// Wit does not say which entities belong to which intent, so every entity\n// of the app is listed.\n")
+	b.WriteString("type Entities struct {\n")
+	for _, f := range fields {
+		fmt.Fprintf(&b, "\t%s []%s `wit:%s`\n", f.name, f.goType, strconv.Quote(f.tag))
+	}
+	b.WriteString("}\n")
+
+	formatted, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v\n%s", err, b.Bytes())
+	}
+	return formatted, nil
+}
+
+func isBuiltin(entity *wit.Entity) bool {
+	return entity.Builtin || strings.HasPrefix(entity.ID, "wit$") || strings.HasPrefix(entity.ID, "wit/")
+}
+
+// The name an entity is reported under in outcomes: built-in entities such
+// as wit$datetime drop their prefix
+func entityName(id string) string {
+	for _, prefix := range []string{"wit$", "wit/"} {
+		id = strings.TrimPrefix(id, prefix)
+	}
+	return id
+}
+
+// Converts a name such as "favorite_city" or "New York" into an exported
+// identifier, e.g. "FavoriteCity" or "NewYork". Names without letters or
+// digits become fallback.
+func identifier(name string, fallback string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	id := b.String()
+	if id == "" {
+		return fallback
+	}
+	if unicode.IsDigit([]rune(id)[0]) {
+		return fallback + id
+	}
+	return id
+}
+
+// Hands out identifiers, numbering any that were already taken
+type namer map[string]bool
+
+func newNamer() namer {
+	return namer{"Entities": true}
+}
+
+func (names namer) unique(name string) string {
+	candidate := name
+	for i := 2; names[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	names[candidate] = true
+	return candidate
+}
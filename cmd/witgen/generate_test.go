@@ -0,0 +1,70 @@
+// Copyright (c) 2014 Jason Goecke
+// generate_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"favorite_city":  "FavoriteCity",
+		"New York":       "NewYork",
+		"recover-pass.2": "RecoverPass2",
+		"Zürich":         "Zürich",
+		"24/7":           "X247",
+		"!!":             "X",
+	}
+	for name, expected := range tests {
+		if actual := identifier(name, "X"); actual != expected {
+			t.Errorf("identifier(%q) = %q, want %q", name, actual, expected)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s := &schema{
+		intents: []string{"book_flight", "recover_password"},
+		entities: []*wit.Entity{
+			{ID: "favorite_city", Values: []wit.EntityValue{{Value: "Paris"}, {Value: "New York"}}},
+			{ID: "seat", Values: []wit.EntityValue{{Value: "aisle"}, {Value: "Aisle"}}},
+			{ID: "phrase"},
+			{ID: "wit$datetime", Builtin: true},
+			{ID: "wit$location", Builtin: true},
+		},
+	}
+	code, err := generate(s, "nlu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"// Code generated by witgen from the Wit app; DO NOT EDIT.\n\npackage nlu\n\nimport \"time\"\n",
+		"\tIntentBookFlight      = \"book_flight\"\n",
+		"type FavoriteCityValue string\n",
+		"\tFavoriteCityNewYork FavoriteCityValue = \"New York\"\n",
+		"\tSeatAisle  SeatValue = \"Aisle\"\n\tSeatAisle2 SeatValue = \"aisle\"\n",
+		"\tFavoriteCity []FavoriteCityValue `wit:\"favorite_city\"`\n",
+		"\tPhrase       []string            `wit:\"phrase\"`\n",
+		"\tDatetime     []time.Time         `wit:\"datetime\"`\n",
+		"\tLocation     []string            `wit:\"location\"`\n",
+	}
+	for _, snippet := range expected {
+		if !strings.Contains(string(code), snippet) {
+			t.Errorf("missing %q in:\n%s", snippet, code)
+		}
+	}
+}
+
+func TestGenerateWithoutTime(t *testing.T) {
+	code, err := generate(&schema{entities: []*wit.Entity{{ID: "phrase"}}}, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(code), "import") || strings.Contains(string(code), "const") {
+		t.Errorf("unexpected code:\n%s", code)
+	}
+}
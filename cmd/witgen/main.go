@@ -0,0 +1,103 @@
+// Copyright (c) 2014 Jason Goecke
+// main.go
+
+// Command witgen generates Go constants and types from a Wit app: a constant
+// per intent, a string type with a constant per value of each entity, and an
+// Entities struct to decode outcomes into with wit.Outcome.Decode.
+//
+//		WIT_ACCESS_TOKEN=<ACCESS-TOKEN> witgen -package nlu -o nlu/wit_gen.go
+//
+// In CI, -check fails when the file is out of date with the app:
+//
+//		witgen -package nlu -o nlu/wit_gen.go -check
+//
+// A go:generate directive keeps the file next to the code using it:
+//
+//		//go:generate witgen -package nlu -o wit_gen.go
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+const usage = `usage: witgen [-package name] [-o file] [-check]
+
+Reads the access token from WIT_ACCESS_TOKEN, and the API base from
+WIT_API_BASE when set.
+`
+
+// Reported by -check when the generated file differs from the app
+var errOutOfDate = errors.New("out of date with the Wit app, run witgen to regenerate it")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "witgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer, getenv func(string) string) error {
+	flags := flag.NewFlagSet("witgen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	// The usage is printed below, to stdout when it was asked for
+	flags.Usage = func() {}
+	pkg := flags.String("package", "main", "package name of the generated file")
+	output := flags.String("o", "", "file to write, standard output when unset")
+	check := flags.Bool("check", false, "fail when the file differs from what would be generated")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			fmt.Fprint(stdout, usage)
+			return nil
+		}
+		fmt.Fprint(stderr, usage)
+		return err
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprint(stderr, usage)
+		return errors.New("unexpected arguments")
+	}
+	if *check && *output == "" {
+		return errors.New("-check needs -o")
+	}
+	token := getenv("WIT_ACCESS_TOKEN")
+	if token == "" {
+		return errors.New("WIT_ACCESS_TOKEN is not set")
+	}
+
+	client := wit.NewClient(token)
+	if base := getenv("WIT_API_BASE"); base != "" {
+		client.APIBase = base
+	}
+	s, err := fetchSchema(client)
+	if err != nil {
+		return err
+	}
+	code, err := generate(s, *pkg)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *check:
+		existing, err := os.ReadFile(*output)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(existing, code) {
+			return fmt.Errorf("%s is %w", *output, errOutOfDate)
+		}
+		return nil
+	case *output != "":
+		return os.WriteFile(*output, code, 0644)
+	}
+	_, err = stdout.Write(code)
+	return err
+}
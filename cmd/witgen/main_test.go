@@ -0,0 +1,93 @@
+// Copyright (c) 2014 Jason Goecke
+// main_test.go
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func runAgainst(t *testing.T, server *wittest.Server, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	env := map[string]string{"WIT_ACCESS_TOKEN": "secret", "WIT_API_BASE": server.URL}
+	err := run(args, &stdout, &stderr, func(key string) string { return env[key] })
+	return stdout.String(), err
+}
+
+// Stores the intents and entities of a small app in a fake Wit API
+func addApp(server *wittest.Server) {
+	server.AddIntent("1", "recover_password", "")
+	server.AddEntity(&wit.Entity{ID: "favorite_city", Values: []wit.EntityValue{{Value: "Paris"}}})
+}
+
+func TestRun(t *testing.T) {
+	server, _ := wittest.NewTestServer(t)
+	addApp(server)
+	out, err := runAgainst(t, server, "-package", "nlu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, snippet := range []string{"package nlu", `IntentRecoverPassword = "recover_password"`, `FavoriteCityParis FavoriteCityValue = "Paris"`} {
+		if !strings.Contains(out, snippet) {
+			t.Errorf("missing %q in:\n%s", snippet, out)
+		}
+	}
+	server.AssertRequested(t, "GET", "/entities/favorite_city")
+}
+
+func TestCheck(t *testing.T) {
+	server, _ := wittest.NewTestServer(t)
+	addApp(server)
+	path := filepath.Join(t.TempDir(), "wit_gen.go")
+	if _, err := runAgainst(t, server, "-o", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runAgainst(t, server, "-o", path, "--check"); err != nil {
+		t.Errorf("fresh file failed the check: %v", err)
+	}
+
+	server.AddIntent("2", "book_flight", "")
+	_, err := runAgainst(t, server, "-o", path, "--check")
+	if !errors.Is(err, errOutOfDate) {
+		t.Errorf("drift was not detected: %v", err)
+	}
+	contents, _ := os.ReadFile(path)
+	if strings.Contains(string(contents), "book_flight") {
+		t.Error("-check wrote the file")
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	server, _ := wittest.NewTestServer(t)
+	addApp(server)
+	if _, err := runAgainst(t, server, "-check"); err == nil || !strings.Contains(err.Error(), "-check needs -o") {
+		t.Errorf("err = %v", err)
+	}
+	server.Script("GET", "/intents", 500, "")
+	if _, err := runAgainst(t, server); err == nil || !strings.Contains(err.Error(), "listing intents") {
+		t.Errorf("err = %v", err)
+	}
+	err := run(nil, &bytes.Buffer{}, &bytes.Buffer{}, func(string) string { return "" })
+	if err == nil || !strings.Contains(err.Error(), "WIT_ACCESS_TOKEN") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	server, _ := wittest.NewTestServer(t)
+	addApp(server)
+	out, err := runAgainst(t, server, "-h")
+	if err != nil || !strings.HasPrefix(out, "usage: witgen") {
+		t.Errorf("-h = %q, %v", out, err)
+	}
+	server.AssertRequestCount(t, 0)
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2014 Jason Goecke
+// decode.go
+
+package wit
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMissingEntity is wrapped by the DecodeError of a required field whose
+// entity is not in the outcome
+var ErrMissingEntity = errors.New("missing required entity")
+
+// DecodeError represents a struct field that could not be filled from an outcome
+type DecodeError struct {
+	Field  string
+	Entity string
+	Value  interface{}
+	Err    error
+}
+
+func (err *DecodeError) Error() string {
+	if errors.Is(err.Err, ErrMissingEntity) {
+		return fmt.Sprintf("wit: %s: %v %q", err.Field, err.Err, err.Entity)
+	}
+	return fmt.Sprintf("wit: %s: entity %q value %v: %v", err.Field, err.Entity, err.Value, err.Err)
+}
+
+func (err *DecodeError) Unwrap() error {
+	return err.Err
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+var messageEntityType = reflect.TypeOf(MessageEntity{})
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Decode fills the tagged fields of the struct v points to from the outcome's
+// entities. A tag names the entity, optionally with a role, and may mark the
+// field as required:
+//
+//		type BookFlight struct {
+//			From  string    `wit:"location:origin,required"`
+//			To    string    `wit:"location:destination,required"`
+//			When  time.Time `wit:"datetime"`
+//			Seats int       `wit:"number"`
+//			Notes []string  `wit:"phrase"`
+//		}
+//		flight := &BookFlight{}
+//		err := outcome.Decode(flight)
+//
+// Fields may be strings, numbers, bools, time.Time, time.Duration (from
+// seconds), MessageEntity, encoding.TextUnmarshaler or pointers to these.
+// Slices receive every value of the entity, other fields the first. Missing
+// optional entities leave the field untouched. Every field that cannot be
+// filled is reported as a *DecodeError, joined into one error.
+func (outcome Outcome) Decode(v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("wit: Decode needs a pointer to a struct, got %T", v)
+	}
+	target = target.Elem()
+	structType := target.Type()
+
+	var errs []error
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, found := field.Tag.Lookup("wit")
+		if !found || tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		required := false
+		for _, option := range strings.Split(options, ",") {
+			required = required || option == "required"
+		}
+		fieldName := structType.Name() + "." + field.Name
+		entities := outcome.lookup(name)
+		if len(entities) == 0 {
+			if required {
+				errs = append(errs, &DecodeError{Field: fieldName, Entity: name, Err: ErrMissingEntity})
+			}
+			continue
+		}
+		if err := setField(target.Field(i), entities); err != nil {
+			err.Field, err.Entity = fieldName, name
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DecodeOutcome decodes an outcome into a new T, as Outcome.Decode does
+//
+//		flight, err := wit.DecodeOutcome[BookFlight](message.Outcomes[0])
+func DecodeOutcome[T any](outcome Outcome) (T, error) {
+	var decoded T
+	err := outcome.Decode(&decoded)
+	return decoded, err
+}
+
+// Finds the entities for a tag name of the form entity or entity:role.
+// Entities with a role are looked up under "entity:role" keys, as later
+// versions of the API report them, then by the Role of entities named entity.
+func (outcome Outcome) lookup(name string) []MessageEntity {
+	if entities := outcome.Entities[name]; len(entities) > 0 {
+		return entities
+	}
+	entity, role, hasRole := strings.Cut(name, ":")
+	if !hasRole {
+		return nil
+	}
+	var matched []MessageEntity
+	for _, candidate := range outcome.Entities[entity] {
+		if candidate.Role != nil && *candidate.Role == role {
+			matched = append(matched, candidate)
+		}
+	}
+	return matched
+}
+
+func setField(field reflect.Value, entities []MessageEntity) *DecodeError {
+	if field.Kind() == reflect.Slice && field.Type() != reflect.TypeOf([]byte(nil)) {
+		values := reflect.MakeSlice(field.Type(), len(entities), len(entities))
+		for i, entity := range entities {
+			if err := setValue(values.Index(i), entity); err != nil {
+				return err
+			}
+		}
+		field.Set(values)
+		return nil
+	}
+	return setValue(field, entities[0])
+}
+
+func setValue(field reflect.Value, entity MessageEntity) *DecodeError {
+	if field.Kind() == reflect.Ptr {
+		value := reflect.New(field.Type().Elem())
+		if err := setValue(value.Elem(), entity); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+	if field.Type() == messageEntityType {
+		field.Set(reflect.ValueOf(entity))
+		return nil
+	}
+
+	raw := entityValue(entity)
+	fail := func(err error) *DecodeError {
+		return &DecodeError{Value: raw, Err: err}
+	}
+	mistyped := fail(fmt.Errorf("cannot decode into %s", field.Type()))
+
+	// Before the TextUnmarshaler check, as time.Time implements it for RFC 3339
+	// only and Wit also returns dates such as 2015-12-01
+	if field.Type() == timeType {
+		text, isString := raw.(string)
+		if !isString {
+			return mistyped
+		}
+		parsed, err := parseTime(text)
+		if err != nil {
+			return fail(err)
+		}
+		field.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+	if field.Type() == durationType {
+		switch typed := raw.(type) {
+		case float64:
+			field.SetInt(int64(typed * float64(time.Second)))
+		case string:
+			parsed, err := time.ParseDuration(typed)
+			if err != nil {
+				return fail(err)
+			}
+			field.SetInt(int64(parsed))
+		default:
+			return mistyped
+		}
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		text, isString := raw.(string)
+		if !isString {
+			return mistyped
+		}
+		if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return fail(err)
+		}
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		switch typed := raw.(type) {
+		case string:
+			field.SetString(typed)
+		case float64, bool:
+			field.SetString(fmt.Sprint(typed))
+		default:
+			return mistyped
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := toFloat(raw)
+		if err != nil || number != math.Trunc(number) || field.OverflowInt(int64(number)) {
+			return mistyped
+		}
+		field.SetInt(int64(number))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, err := toFloat(raw)
+		if err != nil || number < 0 || number != math.Trunc(number) || field.OverflowUint(uint64(number)) {
+			return mistyped
+		}
+		field.SetUint(uint64(number))
+	case reflect.Float32, reflect.Float64:
+		number, err := toFloat(raw)
+		if err != nil {
+			return mistyped
+		}
+		field.SetFloat(number)
+	case reflect.Bool:
+		switch typed := raw.(type) {
+		case bool:
+			field.SetBool(typed)
+		case string:
+			parsed, err := strconv.ParseBool(typed)
+			if err != nil {
+				return mistyped
+			}
+			field.SetBool(parsed)
+		default:
+			return mistyped
+		}
+	case reflect.Interface:
+		if raw == nil {
+			return nil
+		}
+		if !reflect.TypeOf(raw).AssignableTo(field.Type()) {
+			return mistyped
+		}
+		field.Set(reflect.ValueOf(raw))
+	default:
+		return mistyped
+	}
+	return nil
+}
+
+// The value of an entity: its value, the start of an interval, or its body
+func entityValue(entity MessageEntity) interface{} {
+	if entity.Value != nil {
+		return *entity.Value
+	}
+	if entity.From != nil {
+		return entity.From.Value
+	}
+	if entity.Body != nil {
+		return *entity.Body
+	}
+	return nil
+}
+
+func toFloat(raw interface{}) (float64, error) {
+	switch typed := raw.(type) {
+	case float64:
+		return typed, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(typed), 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", raw)
+}
+
+// Parses the datetime values Wit returns, e.g. 2015-12-01T14:00:00.000-08:00
+func parseTime(text string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04:05.000-07:00", time.RFC3339Nano, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a time", text)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2014 Jason Goecke
+// decode_test.go
+
+package wit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bookFlight struct {
+	From     string        `wit:"location:origin,required"`
+	To       *string       `wit:"location:destination"`
+	When     time.Time     `wit:"datetime"`
+	Seats    int           `wit:"number"`
+	Class    seatClass     `wit:"seat_class"`
+	Stay     time.Duration `wit:"duration"`
+	Notes    []string      `wit:"phrase"`
+	Raw      MessageEntity `wit:"datetime"`
+	Ignored  string        `wit:"-"`
+	Untagged string
+}
+
+type seatClass int
+
+func (class *seatClass) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "economy":
+		*class = 1
+	case "business":
+		*class = 2
+	default:
+		return errors.New("unknown seat class")
+	}
+	return nil
+}
+
+func decodeEntity(value interface{}) MessageEntity {
+	return MessageEntity{Value: &value}
+}
+
+func TestDecode(t *testing.T) {
+	origin, destination := "origin", "destination"
+	paris, tokyo := interface{}("Paris"), interface{}("Tokyo")
+	outcome := Outcome{Entities: map[string][]MessageEntity{
+		"location": {
+			{Value: &paris, Role: &origin},
+			{Value: &tokyo, Role: &destination},
+		},
+		"datetime":   {decodeEntity("2015-12-01T14:00:00.000-08:00")},
+		"number":     {decodeEntity(2.0)},
+		"seat_class": {decodeEntity("business")},
+		"duration":   {decodeEntity(5400.0)},
+		"phrase":     {decodeEntity("window seat"), decodeEntity("vegetarian meal")},
+	}}
+
+	flight := &bookFlight{Untagged: "kept"}
+	if err := outcome.Decode(flight); err != nil {
+		t.Fatal(err)
+	}
+	if flight.From != "Paris" || flight.To == nil || *flight.To != "Tokyo" {
+		t.Errorf("From %q, To %v", flight.From, flight.To)
+	}
+	if !flight.When.Equal(time.Date(2015, 12, 1, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("When = %v", flight.When)
+	}
+	if flight.Seats != 2 || flight.Class != 2 || flight.Stay != 90*time.Minute {
+		t.Errorf("Seats %d, Class %d, Stay %v", flight.Seats, flight.Class, flight.Stay)
+	}
+	if strings.Join(flight.Notes, "|") != "window seat|vegetarian meal" {
+		t.Errorf("Notes = %q", flight.Notes)
+	}
+	if flight.Raw.Value == nil || flight.Untagged != "kept" {
+		t.Errorf("flight = %+v", flight)
+	}
+}
+
+func TestDecodeRoleKeys(t *testing.T) {
+	outcome := Outcome{Entities: map[string][]MessageEntity{
+		"location:origin": {decodeEntity("Paris")},
+		"number":          {decodeEntity("3")},
+	}}
+	flight, err := DecodeOutcome[bookFlight](outcome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flight.From != "Paris" || flight.To != nil || flight.Seats != 3 {
+		t.Errorf("flight = %+v", flight)
+	}
+}
+
+func TestDecodeIntervalAndBody(t *testing.T) {
+	body := "tomorrow afternoon"
+	var when struct {
+		Start time.Time `wit:"datetime"`
+		Text  string    `wit:"phrase"`
+	}
+	outcome := Outcome{Entities: map[string][]MessageEntity{
+		"datetime": {{From: &DatetimeIntervalEnd{Value: "2015-12-02T12:00:00.000-08:00", Grain: "hour"}}},
+		"phrase":   {{Body: &body}},
+	}}
+	if err := outcome.Decode(&when); err != nil {
+		t.Fatal(err)
+	}
+	if when.Start.Hour() != 12 || when.Text != body {
+		t.Errorf("decoded %+v", when)
+	}
+}
+
+func TestDecodeTimes(t *testing.T) {
+	var when struct {
+		Day  time.Time  `wit:"day"`
+		At   time.Time  `wit:"at"`
+		Then *time.Time `wit:"then"`
+	}
+	outcome := Outcome{Entities: map[string][]MessageEntity{
+		"day":  {decodeEntity("2015-12-01")},
+		"at":   {decodeEntity("2015-12-01T14:00:00-08:00")},
+		"then": {decodeEntity("2015-12-01T14:00:00.000Z")},
+	}}
+	if err := outcome.Decode(&when); err != nil {
+		t.Fatal(err)
+	}
+	if !when.Day.Equal(time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Day = %v", when.Day)
+	}
+	if !when.At.Equal(time.Date(2015, 12, 1, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("At = %v", when.At)
+	}
+	if when.Then == nil || !when.Then.Equal(time.Date(2015, 12, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("Then = %v", when.Then)
+	}
+}
+
+func TestDecodeRequiredOption(t *testing.T) {
+	var booking struct {
+		From string `wit:"location,omitempty,required"`
+		To   string `wit:"destination,required,omitempty"`
+	}
+	err := Outcome{}.Decode(&booking)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || !errors.Is(err, ErrMissingEntity) || strings.Count(err.Error(), "missing required entity") != 2 {
+		t.Errorf("Expected both entities to be required, got %v", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	outcome := Outcome{Entities: map[string][]MessageEntity{
+		"number":     {decodeEntity("two")},
+		"seat_class": {decodeEntity("first")},
+		"datetime":   {decodeEntity(12.0)},
+	}}
+	err := outcome.Decode(&bookFlight{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !errors.Is(err, ErrMissingEntity) {
+		t.Errorf("missing entity not reported: %v", err)
+	}
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Field != "bookFlight.From" {
+		t.Errorf("first error = %+v", decodeErr)
+	}
+	expected := []string{
+		`wit: bookFlight.From: missing required entity "location:origin"`,
+		`wit: bookFlight.When: entity "datetime" value 12: cannot decode into time.Time`,
+		`wit: bookFlight.Seats: entity "number" value two: cannot decode into int`,
+		`wit: bookFlight.Class: entity "seat_class" value first: unknown seat class`,
+	}
+	for _, message := range expected {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("missing %q in:\n%v", message, err)
+		}
+	}
+
+	if err = outcome.Decode(bookFlight{}); err == nil || !strings.Contains(err.Error(), "pointer to a struct") {
+		t.Errorf("non pointer = %v", err)
+	}
+	var small struct {
+		N int8 `wit:"number"`
+	}
+	big := Outcome{Entities: map[string][]MessageEntity{"number": {decodeEntity(300.0)}}}
+	if err = big.Decode(&small); err == nil {
+		t.Error("expected an overflow error")
+	}
+}
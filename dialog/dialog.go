@@ -0,0 +1,258 @@
+// Copyright (c) 2014 Jason Goecke
+// dialog.go
+
+// Package dialog fills the slots of form-like conversations, such as booking
+// a table, from the entities of successive Wit messages.
+//
+//		manager := dialog.NewManager(dialog.NewMemoryStore(), &dialog.Frame{
+//			Name:   "book_table",
+//			Intent: "book_table",
+//			Slots: []dialog.Slot{
+//				{Name: "date", Entity: "datetime", Prompt: "For which day?", Required: true},
+//				{Name: "party_size", Entity: "number", Prompt: "For how many people?", Required: true},
+//				{Name: "name", Entity: "contact", Prompt: "Under what name?", Required: true},
+//			},
+//		})
+//		turn, err := manager.Handle(ctx, userID, message)
+//		if !turn.Complete {
+//			reply(turn.Prompt)
+//		}
+package dialog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// ErrNoFrame is returned when a message neither continues a conversation nor
+// matches the intent of any frame
+var ErrNoFrame = errors.New("dialog: no frame for message")
+
+// Slot represents a value a Frame needs, filled from a Wit entity
+type Slot struct {
+	Name string
+	// Entity is the key of Outcome.Entities that fills the slot
+	Entity string
+	// Prompt asks the user for the slot, defaults to "What is the <name>?"
+	Prompt   string
+	Required bool
+}
+
+// Frame represents a form made of slots, started by a Wit intent
+type Frame struct {
+	Name string
+	// Intent starts the frame when no other frame is active
+	Intent string
+	Slots  []Slot
+}
+
+// State represents the progress of a conversation through a frame
+type State struct {
+	Frame  string            `json:"frame"`
+	Values map[string]string `json:"values"`
+	// Pending is the slot the last prompt asked for
+	Pending string `json:"pending,omitempty"`
+	// Order lists filled slots from least to most recently filled
+	Order []string `json:"order,omitempty"`
+}
+
+// Turn represents the outcome of handling one message
+type Turn struct {
+	Frame *Frame
+	State *State
+	// Filled lists the slots that received their first value this turn
+	Filled []string
+	// Corrected lists the slots whose value was replaced this turn
+	Corrected []string
+	// Missing lists the required slots that still have no value
+	Missing []string
+	// Prompt asks for the first missing slot, empty when Complete
+	Prompt   string
+	Complete bool
+}
+
+// Manager feeds messages into frames, keeping each conversation's State in a Store
+type Manager struct {
+	Store  Store
+	Frames []*Frame
+}
+
+// NewManager creates a Manager for the given frames
+//
+//		manager := dialog.NewManager(dialog.NewMemoryStore(), bookTable, orderPizza)
+func NewManager(store Store, frames ...*Frame) *Manager {
+	return &Manager{Store: store, Frames: frames}
+}
+
+// Handle fills slots from the top outcome of a message for the conversation
+// and saves its state. A message whose intent starts a different frame
+// replaces the active one. Values for slots that are already filled are
+// treated as corrections ("no, Friday"). The state is deleted from the store
+// once the frame is complete.
+//
+//		turn, err := manager.Handle(ctx, conversationID, message)
+func (manager *Manager) Handle(ctx context.Context, conversationID string, message *wit.Message) (*Turn, error) {
+	state, err := manager.Store.Load(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	var outcome wit.Outcome
+	if len(message.Outcomes) > 0 {
+		outcome = message.Outcomes[0]
+	}
+
+	frame := manager.frame(outcome.Intent)
+	if frame != nil && (state == nil || state.Frame != frame.Name) {
+		state = &State{Frame: frame.Name, Values: map[string]string{}}
+	} else if state != nil {
+		frame = manager.frameNamed(state.Frame)
+	}
+	if frame == nil {
+		return nil, ErrNoFrame
+	}
+
+	turn := &Turn{Frame: frame, State: state}
+	turn.fill(outcome.Entities)
+	turn.Missing = frame.missing(state)
+	turn.Complete = len(turn.Missing) == 0
+	state.Pending = ""
+	if turn.Complete {
+		return turn, manager.Store.Delete(ctx, conversationID)
+	}
+	state.Pending = turn.Missing[0]
+	turn.Prompt = frame.slot(state.Pending).prompt()
+	return turn, manager.Store.Save(ctx, conversationID, state)
+}
+
+// Assigns each entity value to a slot: the pending slot first, then empty
+// slots for the entity in frame order, and finally the most recently filled
+// slot for the entity as a correction
+func (turn *Turn) fill(entities map[string][]wit.MessageEntity) {
+	frame, state := turn.Frame, turn.State
+	used := map[string]int{}
+	for _, slot := range frame.orderedSlots(state.Pending) {
+		values := entities[slot.Entity]
+		if used[slot.Entity] >= len(values) {
+			continue
+		}
+		if _, filled := state.Values[slot.Name]; filled {
+			continue
+		}
+		turn.set(slot.Name, entityValue(values[used[slot.Entity]]))
+		used[slot.Entity]++
+	}
+	for name, values := range entities {
+		if used[name] >= len(values) {
+			continue
+		}
+		for i := len(state.Order) - 1; i >= 0; i-- {
+			if frame.slot(state.Order[i]).Entity == name && !contains(turn.Filled, state.Order[i]) {
+				turn.set(state.Order[i], entityValue(values[used[name]]))
+				break
+			}
+		}
+	}
+}
+
+func (turn *Turn) set(name string, value string) {
+	state := turn.State
+	previous, filled := state.Values[name]
+	state.Values[name] = value
+	for i, ordered := range state.Order {
+		if ordered == name {
+			state.Order = append(state.Order[:i], state.Order[i+1:]...)
+			break
+		}
+	}
+	state.Order = append(state.Order, name)
+	if !filled {
+		turn.Filled = append(turn.Filled, name)
+	} else if previous != value {
+		turn.Corrected = append(turn.Corrected, name)
+	}
+}
+
+// Returns the frame started by an intent
+func (manager *Manager) frame(intent string) *Frame {
+	for _, frame := range manager.Frames {
+		if intent != "" && frame.Intent == intent {
+			return frame
+		}
+	}
+	return nil
+}
+
+func (manager *Manager) frameNamed(name string) *Frame {
+	for _, frame := range manager.Frames {
+		if frame.Name == name {
+			return frame
+		}
+	}
+	return nil
+}
+
+// Lists the slots with the pending one first
+func (frame *Frame) orderedSlots(pending string) []Slot {
+	slots := make([]Slot, 0, len(frame.Slots))
+	for _, slot := range frame.Slots {
+		if slot.Name == pending {
+			slots = append([]Slot{slot}, slots...)
+		} else {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+func (frame *Frame) slot(name string) Slot {
+	for _, slot := range frame.Slots {
+		if slot.Name == name {
+			return slot
+		}
+	}
+	return Slot{}
+}
+
+func (frame *Frame) missing(state *State) []string {
+	var missing []string
+	for _, slot := range frame.Slots {
+		if _, filled := state.Values[slot.Name]; slot.Required && !filled {
+			missing = append(missing, slot.Name)
+		}
+	}
+	return missing
+}
+
+func (slot Slot) prompt() string {
+	if slot.Prompt != "" {
+		return slot.Prompt
+	}
+	return fmt.Sprintf("What is the %s?", slot.Name)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the value of an entity, or the start of an interval
+func entityValue(entity wit.MessageEntity) string {
+	switch {
+	case entity.Value != nil:
+		return fmt.Sprint(*entity.Value)
+	case entity.From != nil:
+		return entity.From.Value
+	case entity.To != nil:
+		return entity.To.Value
+	case entity.Body != nil:
+		return *entity.Body
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2014 Jason Goecke
+// dialog_test.go
+
+package dialog
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+func message(intent string, entities ...string) *wit.Message {
+	outcome := wit.Outcome{Intent: intent, Confidence: 0.9, Entities: map[string][]wit.MessageEntity{}}
+	for i := 0; i < len(entities); i += 2 {
+		value := interface{}(entities[i+1])
+		outcome.Entities[entities[i]] = append(outcome.Entities[entities[i]], wit.MessageEntity{Value: &value})
+	}
+	return &wit.Message{Outcomes: []wit.Outcome{outcome}}
+}
+
+func bookTable() *Frame {
+	return &Frame{
+		Name:   "book_table",
+		Intent: "book_table",
+		Slots: []Slot{
+			{Name: "date", Entity: "datetime", Prompt: "For which day?", Required: true},
+			{Name: "party_size", Entity: "number", Prompt: "For how many people?", Required: true},
+			{Name: "name", Entity: "contact", Required: true},
+			{Name: "notes", Entity: "phrase"},
+		},
+	}
+}
+
+func TestManagerFillsSlots(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	manager := NewManager(store, bookTable())
+
+	turn, err := manager.Handle(ctx, "alice", message("book_table", "datetime", "2015-12-04"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(turn.Filled, ",") != "date" || turn.Prompt != "For how many people?" || turn.Complete {
+		t.Errorf("Unexpected first turn %+v", turn)
+	}
+
+	// An answer with no intent continues the active frame
+	turn, err = manager.Handle(ctx, "alice", message("", "number", "4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn.State.Values["party_size"] != "4" || turn.Prompt != "What is the name?" {
+		t.Errorf("Unexpected second turn %+v", turn)
+	}
+	if strings.Join(turn.Missing, ",") != "name" {
+		t.Errorf("Unexpected missing slots %v", turn.Missing)
+	}
+
+	// "no, Friday" corrects the date while the name is pending
+	turn, err = manager.Handle(ctx, "alice", message("", "datetime", "2015-12-05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(turn.Corrected, ",") != "date" || turn.State.Values["date"] != "2015-12-05" || turn.Prompt != "What is the name?" {
+		t.Errorf("Unexpected correction turn %+v", turn)
+	}
+
+	turn, err = manager.Handle(ctx, "alice", message("", "contact", "Alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !turn.Complete || turn.Prompt != "" || turn.State.Values["name"] != "Alice" {
+		t.Errorf("Unexpected final turn %+v", turn)
+	}
+	if state, _ := store.Load(ctx, "alice"); state != nil {
+		t.Error("Completed conversations should be removed from the store")
+	}
+}
+
+func TestManagerSameEntitySlots(t *testing.T) {
+	frame := &Frame{Name: "flight", Intent: "book_flight", Slots: []Slot{
+		{Name: "origin", Entity: "location", Required: true},
+		{Name: "destination", Entity: "location", Required: true},
+		{Name: "date", Entity: "datetime", Required: true},
+	}}
+	manager := NewManager(NewMemoryStore(), frame)
+	ctx := context.Background()
+
+	turn, err := manager.Handle(ctx, "bob", message("book_flight", "location", "Paris", "location", "Rome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn.State.Values["origin"] != "Paris" || turn.State.Values["destination"] != "Rome" || turn.State.Pending != "date" {
+		t.Errorf("Unexpected turn %+v", turn.State)
+	}
+
+	turn, _ = manager.Handle(ctx, "bob", message("", "location", "Milan"))
+	if turn.State.Values["destination"] != "Milan" || turn.State.Values["origin"] != "Paris" {
+		t.Errorf("A correction should replace the most recently filled slot: %+v", turn.State)
+	}
+}
+
+func TestManagerFrames(t *testing.T) {
+	order := &Frame{Name: "order_pizza", Intent: "order_pizza", Slots: []Slot{{Name: "size", Entity: "size", Required: true}}}
+	manager := NewManager(NewMemoryStore(), bookTable(), order)
+	ctx := context.Background()
+
+	if _, err := manager.Handle(ctx, "carol", message("greeting")); err != ErrNoFrame {
+		t.Errorf("Expected ErrNoFrame, got %v", err)
+	}
+	if _, err := manager.Handle(ctx, "carol", &wit.Message{}); err != ErrNoFrame {
+		t.Errorf("Expected ErrNoFrame, got %v", err)
+	}
+
+	manager.Handle(ctx, "carol", message("book_table", "number", "2"))
+	turn, err := manager.Handle(ctx, "carol", message("order_pizza"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn.Frame.Name != "order_pizza" || len(turn.State.Values) != 0 || turn.Prompt != "What is the size?" {
+		t.Errorf("A new intent should start its own frame: %+v", turn)
+	}
+
+	turn, _ = manager.Handle(ctx, "dave", message("book_table", "number", "2"))
+	if turn.State.Values["party_size"] != "2" {
+		t.Error("Conversations should not share state")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	state := &State{Frame: "book_table", Values: map[string]string{"date": "2015-12-04"}}
+	if err := store.Save(ctx, "alice", state); err != nil {
+		t.Fatal(err)
+	}
+	state.Values["date"] = "changed"
+
+	loaded, err := store.Load(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Values["date"] != "2015-12-04" {
+		t.Error("Saved state should not share memory with the caller")
+	}
+	store.Delete(ctx, "alice")
+	if loaded, _ = store.Load(ctx, "alice"); loaded != nil {
+		t.Error("Deleted state should not load")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2014 Jason Goecke
+// store.go
+
+package dialog
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Store represents where conversation state is kept between messages
+type Store interface {
+	// Load returns the state of a conversation, or nil when it has none
+	Load(ctx context.Context, conversationID string) (*State, error)
+	Save(ctx context.Context, conversationID string, state *State) error
+	Delete(ctx context.Context, conversationID string) error
+}
+
+// MemoryStore is an in-process Store, safe for concurrent use
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore
+//
+//		store := dialog.NewMemoryStore()
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: map[string][]byte{}}
+}
+
+// Load implements Store
+func (store *MemoryStore) Load(ctx context.Context, conversationID string) (*State, error) {
+	store.mu.Lock()
+	data, found := store.states[conversationID]
+	store.mu.Unlock()
+	if !found {
+		return nil, nil
+	}
+	// States are kept encoded so callers never share one
+	state := &State{}
+	err := json.Unmarshal(data, state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Save implements Store
+func (store *MemoryStore) Save(ctx context.Context, conversationID string, state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.states[conversationID] = data
+	return nil
+}
+
+// Delete implements Store
+func (store *MemoryStore) Delete(ctx context.Context, conversationID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.states, conversationID)
+	return nil
+}
@@ -4,6 +4,7 @@
 package wit
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
 	"strings"
@@ -16,6 +17,9 @@ type Entity struct {
 	ID      string        `json:"id"`
 	Name    string        `json:"name,omitempty"`
 	Values  []EntityValue `json:"values"`
+	// Raw is the JSON the entity was parsed from, including any fields this
+	// package does not know about
+	Raw json.RawMessage `json:"-"`
 }
 
 // EntityValue represents a Value within an Entity
@@ -29,6 +33,20 @@ type Expression struct {
 	Expression string `json:"expression"`
 }
 
+// EntityPatch represents a partial update of an entity. Only the fields that
+// are set are sent, so the others are left as they are.
+type EntityPatch struct {
+	Doc    *string        `json:"doc,omitempty"`
+	Values *[]EntityValue `json:"values,omitempty"`
+}
+
+// Deletion represents the acknowledgement of a deleted value or expression
+type Deletion struct {
+	Deleted string `json:"deleted"`
+	// Raw is the JSON the acknowledgement was parsed from
+	Raw json.RawMessage `json:"-"`
+}
+
 // Entities represents a slice of entites when returend as an array (https://wit.ai/docs/api#toc_15)
 type Entities []string
 
@@ -36,59 +54,85 @@ type Entities []string
 //
 //		result, err := client.CreateEntity(entity)
 func (client *Client) CreateEntity(entity *Entity) (*Entity, error) {
+	return client.CreateEntityWithContext(context.Background(), entity)
+}
+
+// CreateEntityWithContext creates a new entity, cancelling the request when ctx
+// is done
+//
+//		result, err := client.CreateEntityWithContext(ctx, entity)
+func (client *Client) CreateEntityWithContext(ctx context.Context, entity *Entity) (*Entity, error) {
 	data, err := json.Marshal(entity)
 	if err != nil {
 		return nil, err
 	}
-	result, err := post(client.APIBase+"/entities", data)
+	result, err := client.post(ctx, client.APIBase+"/entities", data)
 	if err != nil {
 		return nil, err
 	}
-	entity = &Entity{}
-	err = json.Unmarshal(result, entity)
-	return entity, err
+	return client.parseEntity(result)
 }
 
 // CreateEntityValue creates a new entity value (https://wit.ai/docs/api#toc_25)
 //
 //		result, err := client.CreateEntityValue("favorite_city, entityValue)
 func (client *Client) CreateEntityValue(id string, entityValue *EntityValue) (*Entity, error) {
-	data, _ := json.Marshal(entityValue)
-	result, err := post(client.APIBase+"/entities/"+id+"/values", data)
+	return client.CreateEntityValueWithContext(context.Background(), id, entityValue)
+}
+
+// CreateEntityValueWithContext creates a new entity value, cancelling the request
+// when ctx is done
+//
+//		result, err := client.CreateEntityValueWithContext(ctx, "favorite_city", entityValue)
+func (client *Client) CreateEntityValueWithContext(ctx context.Context, id string, entityValue *EntityValue) (*Entity, error) {
+	data, err := json.Marshal(entityValue)
 	if err != nil {
 		return nil, err
 	}
-	entity := &Entity{}
-	err = json.Unmarshal(result, entity)
+	result, err := client.post(ctx, client.APIBase+"/entities/"+id+"/values", data)
 	if err != nil {
 		return nil, err
 	}
-	return entity, nil
+	return client.parseEntity(result)
 }
 
 // CreateEntityValueExp creates a new entity value expression (https://wit.ai/docs/api#toc_25)
 //
 //		result, err := client.CreateEntityValueExp("favorite_city", "Barcelona", "Paella")
 func (client *Client) CreateEntityValueExp(id string, value string, exp string) (*Entity, error) {
-	jsonData, _ := json.Marshal(&Expression{exp})
-	result, err := post(client.APIBase+"/entities/"+id+"/values/"+value+"/expressions", jsonData)
+	return client.CreateEntityValueExpWithContext(context.Background(), id, value, exp)
+}
+
+// CreateEntityValueExpWithContext creates a new entity value expression,
+// cancelling the request when ctx is done
+//
+//		result, err := client.CreateEntityValueExpWithContext(ctx, "favorite_city", "Barcelona", "Paella")
+func (client *Client) CreateEntityValueExpWithContext(ctx context.Context, id string, value string, exp string) (*Entity, error) {
+	jsonData, err := json.Marshal(&Expression{exp})
 	if err != nil {
 		return nil, err
 	}
-	entity := &Entity{}
-	err = json.Unmarshal(result, entity)
+	result, err := client.post(ctx, client.APIBase+"/entities/"+id+"/values/"+value+"/expressions", jsonData)
 	if err != nil {
 		return nil, err
 	}
-	return entity, nil
+	return client.parseEntity(result)
 }
 
 // DeleteEntity deletes an entity (https://wit.ai/docs/api#toc_30)
 //
 //		result, err := client.DeleteEntity("favorite_city")
 func (client *Client) DeleteEntity(id string) error {
+	return client.DeleteEntityWithContext(context.Background(), id)
+}
+
+// DeleteEntityWithContext deletes an entity, cancelling the request when ctx
+// is done
+//
+//		err := client.DeleteEntityWithContext(ctx, "favorite_city")
+func (client *Client) DeleteEntityWithContext(ctx context.Context, id string) error {
 	id = url.QueryEscape(id)
-	_, err := delete(client.APIBase+"/entities", id)
+	_, err := client.delete(ctx, client.APIBase+"/entities", id)
 	if err != nil {
 		return err
 	}
@@ -97,73 +141,136 @@ func (client *Client) DeleteEntity(id string) error {
 
 // DeleteEntityValue deletes an entity's value (https://wit.ai/docs/api#toc_25)
 //
-// 		result, err := client.DeleteEntityValue("favorite_city", "Paris")
-func (client *Client) DeleteEntityValue(id string, value string) ([]byte, error) {
+// 		deletion, err := client.DeleteEntityValue("favorite_city", "Paris")
+func (client *Client) DeleteEntityValue(id string, value string) (*Deletion, error) {
+	return client.DeleteEntityValueWithContext(context.Background(), id, value)
+}
+
+// DeleteEntityValueWithContext deletes an entity's value, cancelling the request
+// when ctx is done
+//
+//		deletion, err := client.DeleteEntityValueWithContext(ctx, "favorite_city", "Paris")
+func (client *Client) DeleteEntityValueWithContext(ctx context.Context, id string, value string) (*Deletion, error) {
 	id = url.QueryEscape(id)
-	result, err := delete(client.APIBase+"/entities", id+"/values/"+value)
+	result, err := client.delete(ctx, client.APIBase+"/entities", id+"/values/"+value)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return client.parseDeletion(result)
 }
 
 // DeleteEntityValueExp deletes an entity's value's expression (https://wit.ai/docs/api#toc_35)
 //
-// 		result, err := client.DeleteEntityValueExp("favorite_city", "Paris", "")
-func (client *Client) DeleteEntityValueExp(id string, value string, exp string) ([]byte, error) {
+// 		deletion, err := client.DeleteEntityValueExp("favorite_city", "Paris", "")
+func (client *Client) DeleteEntityValueExp(id string, value string, exp string) (*Deletion, error) {
+	return client.DeleteEntityValueExpWithContext(context.Background(), id, value, exp)
+}
+
+// DeleteEntityValueExpWithContext deletes an entity's value's expression,
+// cancelling the request when ctx is done
+//
+//		deletion, err := client.DeleteEntityValueExpWithContext(ctx, "favorite_city", "Paris", "Paname")
+func (client *Client) DeleteEntityValueExpWithContext(ctx context.Context, id string, value string, exp string) (*Deletion, error) {
 	id = url.QueryEscape(id)
 	exp = strings.Replace(url.QueryEscape(exp), "+", "%20", -1)
-	result, err := delete(client.APIBase+"/entities", id+"/values/"+value+"/expressions/"+exp)
+	result, err := client.delete(ctx, client.APIBase+"/entities", id+"/values/"+value+"/expressions/"+exp)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return client.parseDeletion(result)
 }
 
 // Entities lists the configured entities (https://wit.ai/docs/api#toc_15)
 //
 //		result, err := client.Entities()
 func (client *Client) Entities() (*Entities, error) {
-	result, err := get(client.APIBase + "/entities")
+	return client.EntitiesWithContext(context.Background())
+}
+
+// EntitiesWithContext lists the configured entities, cancelling the request
+// when ctx is done
+//
+//		result, err := client.EntitiesWithContext(ctx)
+func (client *Client) EntitiesWithContext(ctx context.Context) (*Entities, error) {
+	result, err := client.get(ctx, client.APIBase+"/entities")
 	if err != nil {
 		return nil, err
 	}
-	entities, _ := parseEntities(result)
-	return entities, nil
+	return client.parseEntities(result)
 }
 
 // Entity lists a single configured entity (https://wit.ai/docs/api#toc_17)
 //
 //		result, err := client.Entity("wit$temperature")
 func (client *Client) Entity(id string) (*Entity, error) {
+	return client.EntityWithContext(context.Background(), id)
+}
+
+// EntityWithContext lists a single configured entity, cancelling the request
+// when ctx is done
+//
+//		result, err := client.EntityWithContext(ctx, "wit$temperature")
+func (client *Client) EntityWithContext(ctx context.Context, id string) (*Entity, error) {
 	id = url.QueryEscape(id)
-	result, err := get(client.APIBase + "/entities/" + id)
+	result, err := client.get(ctx, client.APIBase+"/entities/"+id)
 	if err != nil {
 		return nil, err
 	}
-	entity, err := parseEntity(result)
+	return client.parseEntity(result)
+}
+
+// UpdateEntity updates an entity (https://wit.ai/docs/api#toc_22), replacing
+// its doc and values. Use PatchEntity to change only some of them.
+//
+//		result, err := client.UpdateEntity(entity)
+func (client *Client) UpdateEntity(entity *Entity) (*Entity, error) {
+	return client.UpdateEntityWithContext(context.Background(), entity)
+}
+
+// UpdateEntityWithContext updates an entity, replacing its doc and values,
+// cancelling the request when ctx is done
+//
+//		result, err := client.UpdateEntityWithContext(ctx, entity)
+func (client *Client) UpdateEntityWithContext(ctx context.Context, entity *Entity) (*Entity, error) {
+	data, err := json.Marshal(entity)
 	if err != nil {
 		return nil, err
 	}
-	return entity, nil
+	result, err := client.put(ctx, client.APIBase+"/entities/"+url.QueryEscape(entity.ID), data)
+	if err != nil {
+		return nil, err
+	}
+	return client.parseEntity(result)
 }
 
-// UpdateEntity updates and entity (https://wit.ai/docs/api#toc_22)
+// PatchEntity updates only the fields of an entity set in patch
 //
-//		result, err := client.UpdateEntity(entity)
-func (client *Client) UpdateEntity(entity *Entity) ([]byte, error) {
-	data, err := json.Marshal(entity)
-	result, err := put(client.APIBase+"/entities/"+entity.ID, data)
+//		doc := "A city I like"
+//		result, err := client.PatchEntity("favorite_city", &wit.EntityPatch{Doc: &doc})
+func (client *Client) PatchEntity(id string, patch *EntityPatch) (*Entity, error) {
+	return client.PatchEntityWithContext(context.Background(), id, patch)
+}
+
+// PatchEntityWithContext updates only the fields of an entity set in patch,
+// cancelling the request when ctx is done
+//
+//		result, err := client.PatchEntityWithContext(ctx, "favorite_city", patch)
+func (client *Client) PatchEntityWithContext(ctx context.Context, id string, patch *EntityPatch) (*Entity, error) {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.put(ctx, client.APIBase+"/entities/"+url.QueryEscape(id), data)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return client.parseEntity(result)
 }
 
 // Parses the Entities JSON
-func parseEntities(data []byte) (*Entities, error) {
+func (client *Client) parseEntities(data []byte) (*Entities, error) {
 	entities := &Entities{}
-	err := json.Unmarshal(data, entities)
+	err := client.decode(data, entities)
 	if err != nil {
 		return nil, err
 	}
@@ -171,15 +278,33 @@ func parseEntities(data []byte) (*Entities, error) {
 }
 
 // Parses the Entity JSON
-func parseEntity(data []byte) (*Entity, error) {
+func (client *Client) parseEntity(data []byte) (*Entity, error) {
 	entity := &Entity{}
-	err := json.Unmarshal(data, entity)
-	return entity, err
+	err := client.decode(data, entity)
+	if err != nil {
+		return nil, err
+	}
+	entity.Raw = data
+	return entity, nil
+}
+
+// Parses the JSON acknowledging a deletion
+func (client *Client) parseDeletion(data []byte) (*Deletion, error) {
+	deletion := &Deletion{}
+	err := client.decode(data, deletion)
+	if err != nil {
+		return nil, err
+	}
+	deletion.Raw = data
+	return deletion, nil
 }
 
 // Parses the Entities Value JSON
-func parseEntityValue(data []byte) (*EntityValue, error) {
+func (client *Client) parseEntityValue(data []byte) (*EntityValue, error) {
 	entityValue := &EntityValue{}
-	err := json.Unmarshal(data, entityValue)
-	return entityValue, err
+	err := client.decode(data, entityValue)
+	if err != nil {
+		return nil, err
+	}
+	return entityValue, nil
 }
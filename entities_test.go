@@ -1,19 +1,17 @@
 // Copyright (c) 2014 Jason Goecke
 // entities_test.go
 
-package wit
+package wit_test
 
 import (
-	"math/rand"
 	"net/http"
-	"os"
 	"testing"
-	"time"
-)
 
-var entityName string
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
 
-func findEntityValue(e *Entity, v string) (value EntityValue, b bool) {
+func findEntityValue(e *wit.Entity, v string) (value wit.EntityValue, b bool) {
 	for _, val := range e.Values {
 		if val.Value == v {
 			return val, true
@@ -31,66 +29,37 @@ func findStringInArray(a []string, v string) (s string, b bool) {
 	return s, false
 }
 
-func TestWitEntitiesParsing(t *testing.T) {
-	data := `
-	[
-	   "wit$amount_of_money",
-	   "wit$contact",
-	   "wit$datetime",
-	   "wit$on_off",
-	   "wit$phrase_to_translate",
-	   "wit$temperature"
-	]`
-
-	entities, err := parseEntities([]byte(data))
-	if err != nil {
-		t.Error(err.Error())
-	}
-
-	for cnt, value := range *entities {
-		switch cnt {
-		case 0:
-			if value != "wit$amount_of_money" {
-				t.Error("Entities JSON did not parse properly.")
-			}
-		case 3:
-			if value != "wit$on_off" {
-				t.Error("Entities JSON did not parse properly.")
-			}
-		case 5:
-			if value != "wit$temperature" {
-				t.Error("Entities JSON did not parse properly.")
-			}
-		}
-	}
+// Stores the favorite_city entity in a fake Wit API
+func addFavoriteCity(server *wittest.Server) {
+	server.AddEntity(&wit.Entity{
+		ID:     "favorite_city",
+		Doc:    "A city that I like",
+		Values: []wit.EntityValue{{Value: "Paris", Expressions: []string{"Paris", "City of Light", "Capital of France"}}},
+	})
 }
 
-func TestWitEntityParsing(t *testing.T) {
-	data := `
-	{
-	  "builtin": true,
-	  "doc": "Temperature in degrees Celcius or Fahrenheit",
-	  "id": "wit$temperature"
-	}`
+func TestWitEntityRaw(t *testing.T) {
+	data := `{"id":"city","doc":"","values":[],"lookups":["keywords"]}`
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	server.Script("GET", "/entities/city", 200, data)
 
-	entity, err := parseEntity([]byte(data))
+	entity, err := client.Entity("city")
 	if err != nil {
-		t.Error(err.Error())
+		t.Fatal(err)
 	}
-
-	if entity.Builtin != true ||
-		entity.Doc != "Temperature in degrees Celcius or Fahrenheit" ||
-		entity.ID != "wit$temperature" {
-		t.Error("Message JSON did not parse properly.")
+	if string(entity.Raw) != data {
+		t.Errorf("Unexpected raw %s", entity.Raw)
 	}
 }
 
 func TestWitEntities(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	server.AddEntity(&wit.Entity{ID: "wit$age_of_person", Name: "age_of_person", Builtin: true})
 	entities, err := client.Entities()
 	if err != nil {
-		t.Error(err)
-		return
+		t.Fatal(err)
 	}
 
 	ageOfPerson := false
@@ -105,11 +74,12 @@ func TestWitEntities(t *testing.T) {
 }
 
 func TestWitEntity(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	server.AddEntity(&wit.Entity{ID: "wit$age_of_person", Name: "age_of_person", Builtin: true})
 	entity, err := client.Entity("wit$age_of_person")
 	if err != nil {
-		t.Error(err)
-		return
+		t.Fatal(err)
 	}
 	if entity.Name != "age_of_person" ||
 		entity.Builtin != true {
@@ -118,92 +88,71 @@ func TestWitEntity(t *testing.T) {
 
 	// Now test for when the entity is not present
 	_, err = client.Entity("age_of_person")
-	if err.Error() != http.StatusText(404) {
+	if err == nil || err.Error() != http.StatusText(404) {
 		t.Error("Should have returned a not found error")
 	}
-
 }
 
 func TestCreateEntity(t *testing.T) {
-	// Create random string for testing
-	rand.Seed(time.Now().UTC().UnixNano())
-	alpha := "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
-	buf := make([]byte, 10)
-	for i := 0; i < 10; i++ {
-		buf[i] = alpha[rand.Intn(len(alpha))]
-	}
-	entityName = "favorite_city" + string(buf)
-
-	data := `
-	{
-	  "doc": "A city that I like",
-	  "id": "favorite_city",
-	  "values": [
-	    {
-	      "value": "Paris",
-	      "expressions": ["Paris", "City of Light", "Capital of France"]
-	    }
-	  ]
-	}`
-
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	entity, err := parseEntity([]byte(data))
-	if err != nil {
-		t.Error("Did not parse entity properly")
-	}
-	entity.ID = entityName
-	entity, err = client.CreateEntity(entity)
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	entity := &wit.Entity{
+		ID:     "favorite_food",
+		Doc:    "A food that I like",
+		Values: []wit.EntityValue{{Value: "Paella", Expressions: []string{"Paella", "Arroz"}}},
+	}
+	created, err := client.CreateEntity(entity)
 	if err != nil {
-		t.Errorf("Error creating entity %s", err.Error())
-		return
+		t.Fatalf("Error creating entity %s", err.Error())
 	}
-	if entity.Doc != "A city that I like" {
+	if created.Doc != "A food that I like" {
 		t.Error("Entity was not created properly, doc not set")
 	}
-	if entity.Values[0].Value != "Paris" {
+	if created.Values[0].Value != "Paella" {
 		t.Error("Entity was not created properly, values not set")
 	}
+	request := server.AssertRequested(t, "POST", "/entities")
+	if string(request.Body) != `{"doc":"A food that I like","id":"favorite_food","values":[{"value":"Paella","expressions":["Paella","Arroz"]}]}` {
+		t.Errorf("Unexpected request body %s", request.Body)
+	}
 	_, err = client.CreateEntity(entity)
-	if err.Error() != http.StatusText(409) {
+	if err == nil || err.Error() != http.StatusText(409) {
 		t.Error("Expected a 409 since the entity already exists")
 	}
 }
 
 func TestUpdateEntity(t *testing.T) {
-	data := `
-	{
-	  "id": "favorite_city",
-	  "doc": "These are cities worth going to",
-	  "values": [
-	    {
-	      "value": "Paris",
-	      "expressions": ["Paris", "City of Light", "Capital of France"]
-	    },
-	    {
-	      "value": "Seoul",
-	      "expressions": ["Seoul", "서울", "Kimchi paradise"]
-	    }
-	  ]
-	}`
-
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	entity, err := parseEntity([]byte(data))
-	entity.ID = entityName
-	_, err = client.UpdateEntity(entity)
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	entity := &wit.Entity{
+		ID:  "favorite_city",
+		Doc: "These are cities worth going to",
+		Values: []wit.EntityValue{
+			{Value: "Paris", Expressions: []string{"Paris", "City of Light", "Capital of France"}},
+			{Value: "Seoul", Expressions: []string{"Seoul", "서울", "Kimchi paradise"}},
+		},
+	}
+	updated, err := client.UpdateEntity(entity)
 	if err != nil {
-		t.Error("Did not update entity properly")
+		t.Fatal(err)
+	}
+	if updated.Doc != "These are cities worth going to" || len(updated.Values) != 2 {
+		t.Errorf("Did not update entity properly: %+v", updated)
+	}
+	if _, found := findEntityValue(updated, "Seoul"); !found {
+		t.Error("Did not add Seoul to the entity's values")
 	}
 }
 
 func TestCreateEntityValue(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	entityValue := &EntityValue{}
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	entityValue := &wit.EntityValue{}
 	entityValue.Value = "Barcelona"
 	entityValue.Expressions = []string{"Med", "Sagrada Familia", "Gaudi"}
-	entity, err := client.CreateEntityValue(entityName, entityValue)
+	entity, err := client.CreateEntityValue("favorite_city", entityValue)
 	if err != nil {
-		t.Error(err)
-		return
+		t.Fatal(err)
 	}
 	barcelona, found := findEntityValue(entity, "Barcelona")
 	if !found || barcelona.Value != "Barcelona" {
@@ -216,51 +165,68 @@ func TestCreateEntityValue(t *testing.T) {
 }
 
 func TestCreateEntityValueExp(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	entity, err := client.CreateEntityValueExp(entityName, "Barcelona", "Paella")
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	entity, err := client.CreateEntityValueExp("favorite_city", "Paris", "Paname")
 	if err != nil {
-		t.Error(err)
-		return
+		t.Fatal(err)
 	}
-	barcelona, found := findEntityValue(entity, "Barcelona")
-	if !found || barcelona.Value != "Barcelona" {
-		t.Error("Did not add Barcelona to entity's value properly")
+	paris, found := findEntityValue(entity, "Paris")
+	if !found || paris.Value != "Paris" {
+		t.Error("Did not find Paris in the entity's values")
 	}
-	_, found = findStringInArray(barcelona.Expressions, "Paella")
+	_, found = findStringInArray(paris.Expressions, "Paname")
 	if !found {
-		t.Error("Did not add Sagrada Familia to entity's value expression properly")
+		t.Error("Did not add Paname to entity's value expression properly")
 	}
 }
 
 func TestDeleteEntityValueExp(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	_, err := client.DeleteEntityValueExp(entityName, "Paris", "City of Light")
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	deletion, err := client.DeleteEntityValueExp("favorite_city", "Paris", "City of Light")
 	if err != nil {
-		t.Error(err)
-		return
+		t.Fatal(err)
 	}
+	if deletion.Deleted != "City of Light" {
+		t.Errorf("Unexpected deletion %+v", deletion)
+	}
+	server.AssertRequested(t, "DELETE", "/entities/favorite_city/values/Paris/expressions/City of Light")
 }
 
 func TestDeleteEntityValue(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	_, err := client.DeleteEntityValue(entityName, "Paris")
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	deletion, err := client.DeleteEntityValue("favorite_city", "Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deletion.Deleted != "Paris" {
+		t.Errorf("Unexpected deletion %+v", deletion)
+	}
+	entity, err := client.Entity("favorite_city")
 	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := findEntityValue(entity, "Paris"); found {
 		t.Error("Did not delete entity value properly")
 	}
 }
 
 func TestDeleteEntity(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	err := client.DeleteEntity("favorite_city")
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	err := client.DeleteEntity("favorite_food")
 	if err == nil {
 		t.FailNow()
 	}
 	if err.Error() != http.StatusText(404) {
 		t.Error("Delete should have returned 'Entity not found'")
 	}
-	err = client.DeleteEntity(entityName)
-	if err != nil {
-		t.Error(err)
-		return
+	if err = client.DeleteEntity("favorite_city"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Entity("favorite_city"); err == nil || err.Error() != http.StatusText(404) {
+		t.Error("Entity was not deleted")
 	}
 }
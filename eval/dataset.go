@@ -0,0 +1,132 @@
+// Copyright (c) 2014 Jason Goecke
+// dataset.go
+
+package eval
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Example represents a labeled utterance in a dataset
+type Example struct {
+	Text     string           `json:"text"`
+	Intent   string           `json:"intent"`
+	Entities []ExpectedEntity `json:"entities,omitempty"`
+}
+
+// ExpectedEntity represents an entity an Example should be understood to contain.
+// Start and End are byte offsets into the text and are optional.
+type ExpectedEntity struct {
+	Entity string `json:"entity"`
+	Value  string `json:"value,omitempty"`
+	Start  *int   `json:"start,omitempty"`
+	End    *int   `json:"end,omitempty"`
+}
+
+// LoadFile reads a dataset, choosing the format from the .csv or .jsonl extension
+//
+//		examples, err := eval.LoadFile("testdata/intents.jsonl")
+func LoadFile(path string) ([]Example, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return LoadCSV(file)
+	case ".jsonl", ".json":
+		return LoadJSONL(file)
+	}
+	return nil, fmt.Errorf("eval: unknown dataset format %s", path)
+}
+
+// LoadJSONL reads one Example per line, skipping blank lines
+//
+//		{"text": "flights to Paris", "intent": "book_flight", "entities": [{"entity": "location", "value": "Paris", "start": 11, "end": 16}]}
+func LoadJSONL(r io.Reader) ([]Example, error) {
+	var examples []Example
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		example := Example{}
+		if err := json.Unmarshal([]byte(text), &example); err != nil {
+			return nil, fmt.Errorf("eval: line %d: %s", line, err)
+		}
+		if err := example.validate(); err != nil {
+			return nil, fmt.Errorf("eval: line %d: %s", line, err)
+		}
+		examples = append(examples, example)
+	}
+	return examples, scanner.Err()
+}
+
+// LoadCSV reads a dataset with a text,intent[,entities] header. The optional
+// entities column holds entity=value pairs separated by semicolons.
+//
+//		text,intent,entities
+//		flights to Paris tomorrow,book_flight,location=Paris;datetime=tomorrow
+func LoadCSV(r io.Reader) ([]Example, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	textColumn, hasText := columns["text"]
+	intentColumn, hasIntent := columns["intent"]
+	entitiesColumn, hasEntities := columns["entities"]
+	if !hasText || !hasIntent {
+		return nil, fmt.Errorf("eval: CSV header must include text and intent columns")
+	}
+
+	var examples []Example
+	for i, record := range records[1:] {
+		example := Example{Text: record[textColumn], Intent: record[intentColumn]}
+		if hasEntities && record[entitiesColumn] != "" {
+			for _, pair := range strings.Split(record[entitiesColumn], ";") {
+				name, value, _ := strings.Cut(pair, "=")
+				example.Entities = append(example.Entities, ExpectedEntity{Entity: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+			}
+		}
+		if err := example.validate(); err != nil {
+			return nil, fmt.Errorf("eval: row %d: %s", i+2, err)
+		}
+		examples = append(examples, example)
+	}
+	return examples, nil
+}
+
+func (example Example) validate() error {
+	if example.Text == "" {
+		return fmt.Errorf("missing text")
+	}
+	for _, entity := range example.Entities {
+		if entity.Entity == "" {
+			return fmt.Errorf("entity without a name")
+		}
+		if (entity.Start == nil) != (entity.End == nil) {
+			return fmt.Errorf("entity %s must have both start and end", entity.Entity)
+		}
+		if entity.Start != nil && (*entity.Start < 0 || *entity.End > len(example.Text) || *entity.Start >= *entity.End) {
+			return fmt.Errorf("entity %s span [%d:%d] is outside the text", entity.Entity, *entity.Start, *entity.End)
+		}
+	}
+	return nil
+}
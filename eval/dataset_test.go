@@ -0,0 +1,61 @@
+// Copyright (c) 2014 Jason Goecke
+// dataset_test.go
+
+package eval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadJSONL(t *testing.T) {
+	data := `
+{"text": "flights to Paris", "intent": "book_flight", "entities": [{"entity": "location", "value": "Paris", "start": 11, "end": 16}]}
+
+{"text": "hello", "intent": "greeting"}
+`
+	examples, err := LoadJSONL(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 2 {
+		t.Fatalf("not equal %d != %d", 2, len(examples))
+	}
+	entity := examples[0].Entities[0]
+	if entity.Entity != "location" || *entity.Start != 11 || *entity.End != 16 {
+		t.Errorf("Entity did not parse properly: %+v", entity)
+	}
+
+	invalid := []string{
+		`{"text": "flights to Paris", "intent": "book_flight", "entities": [{"entity": "location", "start": 11}]}`,
+		`{"text": "Paris", "intent": "book_flight", "entities": [{"entity": "location", "start": 0, "end": 9}]}`,
+		`{"intent": "greeting"}`,
+		`{"text": `,
+	}
+	for _, line := range invalid {
+		if _, err = LoadJSONL(strings.NewReader(line)); err == nil {
+			t.Errorf("%s should have failed to load", line)
+		}
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	data := "intent,text,entities\nbook_flight,\"flights to Paris, tomorrow\",location=Paris;datetime=tomorrow\ngreeting,hello,\n"
+	examples, err := LoadCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 2 || examples[0].Text != "flights to Paris, tomorrow" || examples[1].Intent != "greeting" {
+		t.Errorf("CSV did not parse properly: %+v", examples)
+	}
+	if len(examples[0].Entities) != 2 || examples[0].Entities[1].Value != "tomorrow" {
+		t.Errorf("CSV entities did not parse properly: %+v", examples[0].Entities)
+	}
+	if len(examples[1].Entities) != 0 {
+		t.Error("Empty entities column should have no entities")
+	}
+
+	if _, err = LoadCSV(strings.NewReader("text,label\nhello,greeting\n")); err == nil {
+		t.Error("A header without an intent column should fail")
+	}
+}
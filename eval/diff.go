@@ -0,0 +1,58 @@
+// Copyright (c) 2014 Jason Goecke
+// diff.go
+
+package eval
+
+import (
+	"sort"
+)
+
+// Diff represents the changes between two evaluation runs
+type Diff struct {
+	AccuracyDelta float64            `json:"accuracy_delta"`
+	F1Delta       map[string]float64 `json:"f1_delta"`
+	// Regressed lists the texts that were understood correctly before but not now
+	Regressed []string `json:"regressed"`
+	// Fixed lists the texts that are understood correctly now but were not before
+	Fixed []string `json:"fixed"`
+}
+
+// Compare reports how the current run changed from the previous one. Examples
+// are matched by text, and only those present in both runs are compared.
+//
+//		previous, err := eval.LoadReport("last-run.json")
+//		diff := eval.Compare(previous, report)
+func Compare(previous *Report, current *Report) *Diff {
+	diff := &Diff{
+		AccuracyDelta: current.Accuracy - previous.Accuracy,
+		F1Delta:       map[string]float64{},
+	}
+	for label, metrics := range current.Intents {
+		diff.F1Delta[label] = metrics.F1 - previous.Intents[label].F1
+	}
+	for label, metrics := range previous.Intents {
+		if _, found := current.Intents[label]; !found {
+			diff.F1Delta[label] = -metrics.F1
+		}
+	}
+
+	before := map[string]bool{}
+	for _, result := range previous.Results {
+		before[result.Example.Text] = result.Correct()
+	}
+	for _, result := range current.Results {
+		wasCorrect, found := before[result.Example.Text]
+		if !found {
+			continue
+		}
+		if wasCorrect && !result.Correct() {
+			diff.Regressed = append(diff.Regressed, result.Example.Text)
+		}
+		if !wasCorrect && result.Correct() {
+			diff.Fixed = append(diff.Fixed, result.Example.Text)
+		}
+	}
+	sort.Strings(diff.Regressed)
+	sort.Strings(diff.Fixed)
+	return diff
+}
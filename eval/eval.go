@@ -0,0 +1,133 @@
+// Copyright (c) 2014 Jason Goecke
+// eval.go
+
+// Package eval runs a labeled dataset through Wit and reports how well the
+// model understands it, for regression testing before promoting a model.
+//
+//		examples, err := eval.LoadFile("testdata/intents.jsonl")
+//		report, err := eval.Run(ctx, client, examples, 4)
+//		fmt.Printf("accuracy %.3f\n", report.Accuracy)
+package eval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// NoIntent is the predicted intent recorded when Wit returns no outcome
+const NoIntent = "(none)"
+
+// Result represents the outcome of running a single Example
+type Result struct {
+	Example    Example           `json:"example"`
+	Intent     string            `json:"intent"`
+	Confidence float64           `json:"confidence"`
+	Entities   []PredictedEntity `json:"entities,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// PredictedEntity represents an entity from the top outcome
+type PredictedEntity struct {
+	Entity string `json:"entity"`
+	Value  string `json:"value,omitempty"`
+	Start  *int   `json:"start,omitempty"`
+	End    *int   `json:"end,omitempty"`
+}
+
+// Correct reports whether the predicted intent matches the expected one
+func (result Result) Correct() bool {
+	return result.Error == "" && result.Intent == result.Example.Intent
+}
+
+// Run sends every example through api.Message, or MessageWithContext with ctx
+// when api supports it, using up to concurrency requests at a time and builds
+// a Report. Results keep the dataset order. Cancelling ctx stops sending new
+// examples and returns ctx.Err().
+//
+//		report, err := eval.Run(ctx, client, examples, 4)
+func Run(ctx context.Context, api wit.MessageAPI, examples []Example, concurrency int) (*Report, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]Result, len(examples))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = runExample(ctx, api, examples[i])
+			}
+		}()
+	}
+
+	var err error
+dispatch:
+	for i := range examples {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return NewReport(results), nil
+}
+
+// Sends a message through api, with ctx when api supports it
+func sendMessage(ctx context.Context, api wit.MessageAPI, request *wit.MessageRequest) (*wit.Message, error) {
+	if api, ok := api.(interface {
+		MessageWithContext(context.Context, *wit.MessageRequest) (*wit.Message, error)
+	}); ok {
+		return api.MessageWithContext(ctx, request)
+	}
+	return api.Message(request)
+}
+
+func runExample(ctx context.Context, api wit.MessageAPI, example Example) Result {
+	result := Result{Example: example, Intent: NoIntent}
+	message, err := sendMessage(ctx, api, &wit.MessageRequest{Query: example.Text})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if len(message.Outcomes) == 0 {
+		return result
+	}
+	outcome := message.Outcomes[0]
+	if outcome.Intent != "" {
+		result.Intent = outcome.Intent
+	}
+	result.Confidence = float64(outcome.Confidence)
+	for name, entities := range outcome.Entities {
+		for _, entity := range entities {
+			predicted := PredictedEntity{Entity: name}
+			if entity.Value != nil {
+				predicted.Value = fmt.Sprint(*entity.Value)
+			}
+			if entity.Start != nil && entity.End != nil {
+				start, end := int(*entity.Start), int(*entity.End)
+				predicted.Start, predicted.End = &start, &end
+			}
+			result.Entities = append(result.Entities, predicted)
+		}
+	}
+	sort.Slice(result.Entities, func(i, j int) bool {
+		a, b := result.Entities[i], result.Entities[j]
+		if a.Start != nil && b.Start != nil && *a.Start != *b.Start {
+			return *a.Start < *b.Start
+		}
+		return a.Entity < b.Entity
+	})
+	return result
+}
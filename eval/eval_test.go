@@ -0,0 +1,200 @@
+// Copyright (c) 2014 Jason Goecke
+// eval_test.go
+
+package eval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/witfake"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func outcome(intent string, confidence float32, entities map[string][]wit.MessageEntity) *wit.Message {
+	return &wit.Message{Outcomes: []wit.Outcome{{Intent: intent, Confidence: confidence, Entities: entities}}}
+}
+
+func location(value string, start int64, end int64) map[string][]wit.MessageEntity {
+	v := interface{}(value)
+	return map[string][]wit.MessageEntity{"location": {{Value: &v, Start: &start, End: &end}}}
+}
+
+func testExamples() []Example {
+	return []Example{
+		{Text: "flights to Paris", Intent: "book_flight", Entities: []ExpectedEntity{{Entity: "location", Value: "Paris", Start: intPtr(11), End: intPtr(16)}}},
+		{Text: "fly to Rome", Intent: "book_flight", Entities: []ExpectedEntity{{Entity: "location", Value: "Rome", Start: intPtr(7), End: intPtr(11)}}},
+		{Text: "hello", Intent: "greeting"},
+		{Text: "hi there", Intent: "greeting"},
+		{Text: "gibberish", Intent: "greeting"},
+		{Text: "broken", Intent: "greeting"},
+	}
+}
+
+func testFake() *witfake.Fake {
+	fake := witfake.New()
+	fake.AddMessage("flights to Paris", outcome("book_flight", 0.95, location("Paris", 11, 16)))
+	fake.AddMessage("fly to Rome", outcome("book_flight", 0.75, location("Rome", 6, 11)))
+	fake.AddMessage("hello", outcome("greeting", 0.92, nil))
+	fake.AddMessage("hi there", outcome("book_flight", 0.55, nil))
+	fake.AddMessage("gibberish", &wit.Message{})
+	return fake
+}
+
+func TestRun(t *testing.T) {
+	var inflight, peak int32
+	canned := testFake()
+	fake := witfake.New()
+	fake.MessageFunc = func(request *wit.MessageRequest) (*wit.Message, error) {
+		current := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			seen := atomic.LoadInt32(&peak)
+			if current <= seen || atomic.CompareAndSwapInt32(&peak, seen, current) {
+				break
+			}
+		}
+		if request.Query == "broken" {
+			return nil, errors.New("Internal Server Error")
+		}
+		return canned.Message(request)
+	}
+
+	report, err := Run(context.Background(), fake, testExamples(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak > 2 {
+		t.Errorf("Concurrency was not bounded, saw %d requests at once", peak)
+	}
+	if report.Total != 6 || report.Correct != 3 || report.Errors != 1 {
+		t.Errorf("Unexpected totals %d/%d/%d", report.Total, report.Correct, report.Errors)
+	}
+	if report.Accuracy != 0.5 {
+		t.Errorf("not equal %f != %f", 0.5, report.Accuracy)
+	}
+	if report.Results[2].Example.Text != "hello" || report.Results[4].Intent != NoIntent {
+		t.Error("Results did not keep the dataset order")
+	}
+
+	greeting := report.Intents["greeting"]
+	if greeting.Support != 3 || greeting.TruePositives != 1 || greeting.FalseNegatives != 2 || greeting.Precision != 1 {
+		t.Errorf("Unexpected greeting metrics %+v", greeting)
+	}
+	bookFlight := report.Intents["book_flight"]
+	if bookFlight.Precision != 2.0/3 || bookFlight.Recall != 1 || math.Abs(bookFlight.F1-0.8) > 1e-9 {
+		t.Errorf("Unexpected book_flight metrics %+v", bookFlight)
+	}
+	if report.Confusion["greeting"]["book_flight"] != 1 || report.Confusion["greeting"][NoIntent] != 1 {
+		t.Errorf("Unexpected confusion matrix %v", report.Confusion)
+	}
+	if strings.Join(report.Labels, ",") != "(none),book_flight,greeting" {
+		t.Errorf("Unexpected labels %v", report.Labels)
+	}
+
+	entities := report.Entities
+	if entities.Expected != 2 || entities.Found != 2 || entities.SpanCorrect != 1 || entities.ValueCorrect != 2 || entities.SpanAccuracy != 0.5 {
+		t.Errorf("Unexpected entity metrics %+v", entities)
+	}
+
+	if report.Calibration[9].Count != 2 || report.Calibration[9].Correct != 2 || report.Calibration[5].Correct != 0 {
+		t.Errorf("Unexpected calibration %+v", report.Calibration)
+	}
+	if report.Calibration[0].Count != 1 || report.ExpectedCalibrationError <= 0 {
+		t.Errorf("Unexpected calibration error %f", report.ExpectedCalibrationError)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Run(ctx, testFake(), testExamples(), 1)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+type exampleKey struct{}
+
+// Answers like a Fake, failing unless MessageWithContext gets the ctx given
+// to Run
+type contextAPI struct {
+	*witfake.Fake
+}
+
+func (api contextAPI) MessageWithContext(ctx context.Context, request *wit.MessageRequest) (*wit.Message, error) {
+	if ctx.Value(exampleKey{}) != "eval" {
+		return nil, errors.New("missing context")
+	}
+	return api.Message(request)
+}
+
+func TestRunWithContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), exampleKey{}, "eval")
+	report, err := Run(ctx, contextAPI{testFake()}, testExamples()[:1], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Errors != 0 || report.Correct != 1 {
+		t.Errorf("Unexpected results %+v", report.Results)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	previous := NewReport([]Result{
+		{Example: Example{Text: "hello", Intent: "greeting"}, Intent: "greeting"},
+		{Example: Example{Text: "hi there", Intent: "greeting"}, Intent: "greeting"},
+		{Example: Example{Text: "bye", Intent: "goodbye"}, Intent: "greeting"},
+	})
+	current := NewReport([]Result{
+		{Example: Example{Text: "hello", Intent: "greeting"}, Intent: "greeting"},
+		{Example: Example{Text: "hi there", Intent: "greeting"}, Intent: "goodbye"},
+		{Example: Example{Text: "bye", Intent: "goodbye"}, Intent: "goodbye"},
+		{Example: Example{Text: "new", Intent: "goodbye"}, Intent: "goodbye"},
+	})
+	diff := Compare(previous, current)
+	if strings.Join(diff.Regressed, ",") != "hi there" || strings.Join(diff.Fixed, ",") != "bye" {
+		t.Errorf("Unexpected diff %+v", diff)
+	}
+	if math.Abs(diff.AccuracyDelta-(0.75-2.0/3)) > 1e-9 {
+		t.Errorf("Unexpected accuracy delta %f", diff.AccuracyDelta)
+	}
+	if diff.F1Delta["goodbye"] <= 0 {
+		t.Errorf("Unexpected F1 delta %v", diff.F1Delta)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	report := NewReport([]Result{
+		{Example: Example{Text: "hello", Intent: "greeting"}, Intent: "greeting"},
+		{Example: Example{Text: "hi <there>", Intent: "greeting"}, Intent: "goodbye", Confidence: 0.4},
+		{Example: Example{Text: "Paris", Intent: "book_flight", Entities: []ExpectedEntity{{Entity: "location", Value: "Paris"}}}, Intent: "book_flight"},
+		{Example: Example{Text: "broken", Intent: "greeting"}, Error: "Internal Server Error"},
+	})
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, expected := range []string{
+		`<testsuite name="wit-eval" tests="4" failures="2" errors="1">`,
+		`<testcase classname="greeting" name="hello"></testcase>`,
+		`name="hi &lt;there&gt;">`,
+		`<failure message="expected intent greeting, got goodbye (0.400)"></failure>`,
+		`<failure message="expected entities [location=Paris], got []"></failure>`,
+		`<error message="Internal Server Error"></error>`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected JUnit XML to contain %s, got:\n%s", expected, out)
+		}
+	}
+}
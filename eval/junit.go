@@ -0,0 +1,85 @@
+// Copyright (c) 2014 Jason Goecke
+// junit.go
+
+package eval
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the report as JUnit XML with one test case per example,
+// classed by expected intent. An example fails when its intent or any of its
+// expected entities is wrong.
+//
+//		err := eval.WriteJUnit(file, report)
+func WriteJUnit(w io.Writer, report *Report) error {
+	suite := junitTestSuite{Name: "wit-eval", Tests: len(report.Results)}
+	for _, result := range report.Results {
+		testCase := junitTestCase{ClassName: result.Example.Intent, Name: result.Example.Text}
+		switch {
+		case result.Error != "":
+			testCase.Error = &junitMessage{result.Error}
+			suite.Errors++
+		case !result.Correct() || !result.EntitiesCorrect():
+			testCase.Failure = &junitMessage{failureMessage(result)}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func failureMessage(result Result) string {
+	var problems []string
+	if !result.Correct() {
+		problems = append(problems, fmt.Sprintf("expected intent %s, got %s (%.3f)", result.Example.Intent, result.Intent, result.Confidence))
+	}
+	if !result.EntitiesCorrect() {
+		var expected, predicted []string
+		for _, entity := range result.Example.Entities {
+			expected = append(expected, entity.Entity+"="+entity.Value)
+		}
+		for _, entity := range result.Entities {
+			predicted = append(predicted, entity.Entity+"="+entity.Value)
+		}
+		problems = append(problems, fmt.Sprintf("expected entities [%s], got [%s]", strings.Join(expected, " "), strings.Join(predicted, " ")))
+	}
+	return strings.Join(problems, "; ")
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2014 Jason Goecke
+// report.go
+
+package eval
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+)
+
+// CalibrationBins is the number of equal width confidence bins in a Report
+const CalibrationBins = 10
+
+// Report represents the metrics computed from a set of Results
+type Report struct {
+	Total    int     `json:"total"`
+	Correct  int     `json:"correct"`
+	Errors   int     `json:"errors"`
+	Accuracy float64 `json:"accuracy"`
+	// Intents holds per intent metrics for every expected or predicted intent
+	Intents map[string]IntentMetrics `json:"intents"`
+	// Labels lists the intents of the confusion matrix in sorted order
+	Labels []string `json:"labels"`
+	// Confusion counts results by expected then predicted intent
+	Confusion map[string]map[string]int `json:"confusion"`
+	Entities  EntityMetrics             `json:"entities"`
+	// Calibration buckets results by confidence to compare it with accuracy
+	Calibration []CalibrationBin `json:"calibration"`
+	// ExpectedCalibrationError is the count weighted mean gap between
+	// confidence and accuracy across the calibration bins
+	ExpectedCalibrationError float64  `json:"expected_calibration_error"`
+	Results                  []Result `json:"results"`
+}
+
+// IntentMetrics represents the classification metrics of a single intent
+type IntentMetrics struct {
+	Support        int     `json:"support"`
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+// EntityMetrics represents how well expected entities were found. Spans and
+// values are only scored for expected entities that specify them.
+type EntityMetrics struct {
+	Expected      int     `json:"expected"`
+	Found         int     `json:"found"`
+	Spurious      int     `json:"spurious"`
+	SpanTotal     int     `json:"span_total"`
+	SpanCorrect   int     `json:"span_correct"`
+	SpanAccuracy  float64 `json:"span_accuracy"`
+	ValueTotal    int     `json:"value_total"`
+	ValueCorrect  int     `json:"value_correct"`
+	ValueAccuracy float64 `json:"value_accuracy"`
+}
+
+// CalibrationBin represents the results whose confidence fell in [Lower, Upper)
+type CalibrationBin struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	Count          int     `json:"count"`
+	Correct        int     `json:"correct"`
+	MeanConfidence float64 `json:"mean_confidence"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+// NewReport computes the metrics for a set of results. Results with an error
+// count against accuracy but are left out of the confusion matrix.
+//
+//		report := eval.NewReport(results)
+func NewReport(results []Result) *Report {
+	report := &Report{
+		Total:     len(results),
+		Intents:   map[string]IntentMetrics{},
+		Confusion: map[string]map[string]int{},
+		Results:   results,
+	}
+	labels := map[string]bool{}
+	for i := 0; i < CalibrationBins; i++ {
+		report.Calibration = append(report.Calibration, CalibrationBin{
+			Lower: float64(i) / CalibrationBins,
+			Upper: float64(i+1) / CalibrationBins,
+		})
+	}
+
+	for _, result := range results {
+		if result.Error != "" {
+			report.Errors++
+			continue
+		}
+		if result.Correct() {
+			report.Correct++
+		}
+		expected, predicted := result.Example.Intent, result.Intent
+		labels[expected], labels[predicted] = true, true
+		if report.Confusion[expected] == nil {
+			report.Confusion[expected] = map[string]int{}
+		}
+		report.Confusion[expected][predicted]++
+
+		bin := &report.Calibration[calibrationBin(result.Confidence)]
+		bin.Count++
+		bin.MeanConfidence += result.Confidence
+		if result.Correct() {
+			bin.Correct++
+		}
+		report.Entities.add(result)
+	}
+
+	for label := range labels {
+		report.Labels = append(report.Labels, label)
+	}
+	sort.Strings(report.Labels)
+	for _, label := range report.Labels {
+		report.Intents[label] = report.intentMetrics(label)
+	}
+	for i := range report.Calibration {
+		bin := &report.Calibration[i]
+		if bin.Count > 0 {
+			bin.MeanConfidence /= float64(bin.Count)
+			bin.Accuracy = float64(bin.Correct) / float64(bin.Count)
+			scored := report.Total - report.Errors
+			report.ExpectedCalibrationError += float64(bin.Count) / float64(scored) * math.Abs(bin.Accuracy-bin.MeanConfidence)
+		}
+	}
+	report.Accuracy = ratio(report.Correct, report.Total)
+	report.Entities.SpanAccuracy = ratio(report.Entities.SpanCorrect, report.Entities.SpanTotal)
+	report.Entities.ValueAccuracy = ratio(report.Entities.ValueCorrect, report.Entities.ValueTotal)
+	return report
+}
+
+// LoadReport reads a report previously written as JSON, for use with Compare
+//
+//		previous, err := eval.LoadReport("last-run.json")
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{}
+	err = json.Unmarshal(data, report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (report *Report) intentMetrics(label string) IntentMetrics {
+	metrics := IntentMetrics{}
+	for expected, row := range report.Confusion {
+		for predicted, count := range row {
+			switch {
+			case expected == label && predicted == label:
+				metrics.TruePositives += count
+			case predicted == label:
+				metrics.FalsePositives += count
+			case expected == label:
+				metrics.FalseNegatives += count
+			}
+			if expected == label {
+				metrics.Support += count
+			}
+		}
+	}
+	metrics.Precision = ratio(metrics.TruePositives, metrics.TruePositives+metrics.FalsePositives)
+	metrics.Recall = ratio(metrics.TruePositives, metrics.TruePositives+metrics.FalseNegatives)
+	if metrics.Precision+metrics.Recall > 0 {
+		metrics.F1 = 2 * metrics.Precision * metrics.Recall / (metrics.Precision + metrics.Recall)
+	}
+	return metrics
+}
+
+// Scores the expected entities of a result against its predicted ones
+func (metrics *EntityMetrics) add(result Result) {
+	used := make([]bool, len(result.Entities))
+	for _, expected := range result.Example.Entities {
+		metrics.Expected++
+		if expected.Start != nil {
+			metrics.SpanTotal++
+		}
+		if expected.Value != "" {
+			metrics.ValueTotal++
+		}
+		match := matchEntity(expected, result.Entities, used)
+		if match < 0 {
+			continue
+		}
+		used[match] = true
+		metrics.Found++
+		predicted := result.Entities[match]
+		if expected.Start != nil && spanEqual(expected, predicted) {
+			metrics.SpanCorrect++
+		}
+		if expected.Value != "" && expected.Value == predicted.Value {
+			metrics.ValueCorrect++
+		}
+	}
+	for _, matched := range used {
+		if !matched {
+			metrics.Spurious++
+		}
+	}
+}
+
+// Finds the best unused predicted entity with the same name, preferring an
+// exact span then an exact value
+func matchEntity(expected ExpectedEntity, predicted []PredictedEntity, used []bool) int {
+	best, bestScore := -1, -1
+	for i, entity := range predicted {
+		if used[i] || entity.Entity != expected.Entity {
+			continue
+		}
+		score := 0
+		if expected.Start != nil && spanEqual(expected, entity) {
+			score += 2
+		}
+		if expected.Value != "" && expected.Value == entity.Value {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func spanEqual(expected ExpectedEntity, predicted PredictedEntity) bool {
+	return predicted.Start != nil && predicted.End != nil &&
+		*expected.Start == *predicted.Start && *expected.End == *predicted.End
+}
+
+// EntitiesCorrect reports whether every expected entity was found with its
+// expected span and value
+func (result Result) EntitiesCorrect() bool {
+	metrics := EntityMetrics{}
+	metrics.add(result)
+	return metrics.Found == metrics.Expected &&
+		metrics.SpanCorrect == metrics.SpanTotal &&
+		metrics.ValueCorrect == metrics.ValueTotal
+}
+
+func calibrationBin(confidence float64) int {
+	bin := int(confidence * CalibrationBins)
+	if bin < 0 {
+		return 0
+	}
+	if bin >= CalibrationBins {
+		return CalibrationBins - 1
+	}
+	return bin
+}
+
+func ratio(numerator int, denominator int) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
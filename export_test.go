@@ -0,0 +1,28 @@
+// Copyright (c) 2014 Jason Goecke
+// export_test.go
+
+package wit
+
+import (
+	"os"
+	"time"
+)
+
+// SetCheckpointOpener replaces how BatchMessage opens checkpoint files until
+// the returned function is called
+func SetCheckpointOpener(open func(string, int, os.FileMode) (*os.File, error)) func() {
+	previous := openCheckpointFile
+	openCheckpointFile = open
+	return func() { openCheckpointFile = previous }
+}
+
+// SetClock replaces the clock an LRUCache uses to expire messages
+func (cache *LRUCache) SetClock(now func() time.Time) {
+	cache.now = now
+}
+
+// Endpoint and ErrorClass expose how requests are labelled for Metrics
+var (
+	Endpoint   = endpoint
+	ErrorClass = errorClass
+)
@@ -4,35 +4,55 @@
 package wit
 
 import (
+	"context"
 	"encoding/json"
 )
 
 // Intents represents intents in the Wit API (https://wit.ai/docs/api#toc_13)
-type Intents []struct {
+type Intents []Intent
+
+// Intent represents a single intent within Intents
+type Intent struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
 	Doc      string `json:"doc"`
 	Metadata string `json:"metadata"`
+	// Raw is the JSON the intent was parsed from, including any fields this
+	// package does not know about
+	Raw json.RawMessage `json:"-"`
 }
 
 // Intents lists intents configured in the Wit API (https://wit.ai/docs/api#toc_13)
 //
 //		result, err := client.Intents()
 func (client *Client) Intents() (*Intents, error) {
-	result, err := get(client.APIBase + "/intents")
+	return client.IntentsWithContext(context.Background())
+}
+
+// IntentsWithContext lists the configured intents, cancelling the request when
+// ctx is done
+//
+//		result, err := client.IntentsWithContext(ctx)
+func (client *Client) IntentsWithContext(ctx context.Context) (*Intents, error) {
+	result, err := client.get(ctx, client.APIBase+"/intents")
 	if err != nil {
 		return nil, err
 	}
-	intents, _ := parseIntents(result)
-	return intents, nil
+	return client.parseIntents(result)
 }
 
 // Parses the JSON for an Intent
-func parseIntents(data []byte) (*Intents, error) {
+func (client *Client) parseIntents(data []byte) (*Intents, error) {
 	intents := &Intents{}
-	err := json.Unmarshal(data, intents)
+	err := client.decode(data, intents)
 	if err != nil {
 		return nil, err
 	}
+	raw := []json.RawMessage{}
+	if json.Unmarshal(data, &raw) == nil && len(raw) == len(*intents) {
+		for i := range raw {
+			(*intents)[i].Raw = raw[i]
+		}
+	}
 	return intents, nil
 }
@@ -0,0 +1,50 @@
+// Copyright (c) 2014 Jason Goecke
+// interfaces.go
+
+package wit
+
+// MessageAPI represents the text message endpoints, for consumers that want
+// to substitute a stub for *Client in their own tests
+type MessageAPI interface {
+	Message(request *MessageRequest) (*Message, error)
+	Messages(id string) (*Message, error)
+}
+
+// SpeechAPI represents the speech endpoint
+type SpeechAPI interface {
+	AudioMessage(request *MessageRequest) (*Message, error)
+}
+
+// EntityAPI represents the entity, value and expression endpoints
+type EntityAPI interface {
+	Entities() (*Entities, error)
+	Entity(id string) (*Entity, error)
+	CreateEntity(entity *Entity) (*Entity, error)
+	UpdateEntity(entity *Entity) (*Entity, error)
+	PatchEntity(id string, patch *EntityPatch) (*Entity, error)
+	DeleteEntity(id string) error
+	CreateEntityValue(id string, entityValue *EntityValue) (*Entity, error)
+	DeleteEntityValue(id string, value string) (*Deletion, error)
+	CreateEntityValueExp(id string, value string, exp string) (*Entity, error)
+	DeleteEntityValueExp(id string, value string, exp string) (*Deletion, error)
+}
+
+// IntentAPI represents the intent endpoints
+type IntentAPI interface {
+	Intents() (*Intents, error)
+}
+
+// API represents the full Wit API as implemented by *Client
+//
+//		type Handler struct {
+//			Wit wit.API
+//		}
+//		handler := &Handler{Wit: wit.NewClient("<ACCESS-TOKEN>")}
+type API interface {
+	MessageAPI
+	SpeechAPI
+	EntityAPI
+	IntentAPI
+}
+
+var _ API = (*Client)(nil)
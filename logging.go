@@ -0,0 +1,180 @@
+// Copyright (c) 2014 Jason Goecke
+// logging.go
+
+package wit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLogBodyLimit is the number of body bytes logged when Client.LogBodyLimit is zero
+const DefaultLogBodyLimit = 1000
+
+// Redacted replaces secrets and, with Client.RedactUserText, user text in logs
+const Redacted = "[REDACTED]"
+
+// Fields of Wit responses holding the user's text
+var userTextFields = map[string]bool{"_text": true, "body": true}
+
+// Fields of message entities holding values resolved from the user's text,
+// such as the number in "my card is 4111"
+var entityValueFields = map[string]bool{"value": true, "suggested": true}
+
+// Query parameters of message requests carrying user text: the message, its
+// context and the keywords of dynamic entities, such as contact names
+var userTextParams = []string{"q", "context", "entities"}
+
+// Returns the logger for requests: Client.Logger, or with GOWIT_DEBUG=true and
+// no Logger, a text logger on stdout at debug level
+func (client *Client) logger() *slog.Logger {
+	if client.Logger != nil {
+		return client.Logger
+	}
+	if os.Getenv("GOWIT_DEBUG") == "true" {
+		return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return nil
+}
+
+// Logs an outgoing request at debug level
+func (client *Client) logRequest(ctx context.Context, logger *slog.Logger, req *http.Request, httpParams *HTTPParams) {
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "wit request",
+		slog.String("method", req.Method),
+		slog.String("url", client.redactURL(req.URL)),
+		headersAttr(req.Header),
+		slog.String("body", client.logBody(httpParams.ContentType, httpParams.Data)),
+	)
+}
+
+// Logs a response at debug level, or at warn level when it is not a 200
+func (client *Client) logResponse(ctx context.Context, logger *slog.Logger, req *http.Request, result *http.Response, body []byte, elapsed time.Duration) {
+	level := slog.LevelDebug
+	if result.StatusCode != http.StatusOK {
+		level = slog.LevelWarn
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	logger.LogAttrs(ctx, level, "wit response",
+		slog.String("method", req.Method),
+		slog.String("url", client.redactURL(req.URL)),
+		slog.Int("status", result.StatusCode),
+		slog.Duration("duration", elapsed),
+		headersAttr(result.Header),
+		slog.String("body", client.logBody(result.Header.Get("Content-Type"), body)),
+	)
+}
+
+// Logs a request that got no response at error level
+func (client *Client) logError(ctx context.Context, logger *slog.Logger, req *http.Request, err error, elapsed time.Duration) {
+	logger.LogAttrs(ctx, slog.LevelError, "wit request failed",
+		slog.String("method", req.Method),
+		slog.String("url", client.redactURL(req.URL)),
+		slog.Duration("duration", elapsed),
+		slog.String("error", err.Error()),
+	)
+}
+
+// Groups headers, sorted, with the Authorization header redacted
+func headersAttr(header http.Header) slog.Attr {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attrs := make([]slog.Attr, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header.Values(name), ", ")
+		if http.CanonicalHeaderKey(name) == "Authorization" {
+			value = Redacted
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Attr{Key: "headers", Value: slog.GroupValue(attrs...)}
+}
+
+// Redacts the user text parameters of a message request when RedactUserText
+// is set
+func (client *Client) redactURL(u *url.URL) string {
+	if !client.RedactUserText {
+		return u.String()
+	}
+	query := u.Query()
+	changed := false
+	for _, param := range userTextParams {
+		if query.Has(param) {
+			query.Set(param, Redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// Describes binary bodies by size, redacts user text from JSON bodies when
+// RedactUserText is set, and truncates to LogBodyLimit
+func (client *Client) logBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(contentType, "audio/") {
+		return fmt.Sprintf("<%d bytes of %s>", len(body), contentType)
+	}
+	if client.RedactUserText {
+		var decoded interface{}
+		if json.Unmarshal(body, &decoded) == nil {
+			if redacted, err := json.Marshal(redactUserText(decoded, false)); err == nil {
+				body = redacted
+			}
+		}
+	}
+	limit := client.LogBodyLimit
+	if limit == 0 {
+		limit = DefaultLogBodyLimit
+	}
+	if limit > 0 && len(body) > limit {
+		return fmt.Sprintf("%s... (%d bytes truncated)", body[:limit], len(body)-limit)
+	}
+	return string(body)
+}
+
+// Replaces the string values of user text fields throughout a decoded JSON
+// value, and within message entities any value resolved from the text
+func redactUserText(value interface{}, inEntities bool) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, field := range typed {
+			_, isString := field.(string)
+			_, isFlag := field.(bool)
+			switch {
+			case isString && userTextFields[key]:
+				typed[key] = Redacted
+			case inEntities && !isFlag && entityValueFields[key]:
+				typed[key] = Redacted
+			default:
+				typed[key] = redactUserText(field, inEntities || key == "entities")
+			}
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = redactUserText(item, inEntities)
+		}
+	}
+	return value
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2014 Jason Goecke
+// logging_test.go
+
+package wit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+// Runs a message request against a fake Wit API answering with status and
+// body, returning the decoded log records
+func logMessage(t *testing.T, configure func(*wit.Client), level slog.Level, status int, body string) []map[string]interface{} {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.Script("GET", "/message", status, body)
+	var buf bytes.Buffer
+	client := server.Client()
+	client.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level}))
+	if configure != nil {
+		configure(client)
+	}
+	client.Message(&wit.MessageRequest{Query: "my card is 4111"})
+	return logRecords(t, &buf)
+}
+
+// Decodes the records a JSON slog handler wrote to buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLoggingRequestAndResponse(t *testing.T) {
+	records := logMessage(t, nil, slog.LevelDebug, 200, `{"msg_id":"1","_text":"my card is 4111"}`)
+	if len(records) != 2 || records[0]["msg"] != "wit request" || records[1]["msg"] != "wit response" {
+		t.Fatalf("records = %v", records)
+	}
+	request := records[0]
+	if request["level"] != "DEBUG" || !strings.Contains(request["url"].(string), "q=my+card+is+4111") {
+		t.Errorf("request = %v", request)
+	}
+	if auth := request["headers"].(map[string]interface{})["Authorization"]; auth != wit.Redacted {
+		t.Errorf("Authorization = %v", auth)
+	}
+	response := records[1]
+	if response["status"] != float64(200) || response["body"] != `{"msg_id":"1","_text":"my card is 4111"}` {
+		t.Errorf("response = %v", response)
+	}
+}
+
+func TestLoggingLevels(t *testing.T) {
+	if records := logMessage(t, nil, slog.LevelInfo, 200, `{}`); len(records) != 0 {
+		t.Errorf("successful requests logged at info: %v", records)
+	}
+	records := logMessage(t, nil, slog.LevelInfo, 500, `{"error":"boom"}`)
+	if len(records) != 1 || records[0]["level"] != "WARN" || records[0]["status"] != float64(500) {
+		t.Errorf("records = %v", records)
+	}
+
+	var buf bytes.Buffer
+	client := wit.NewClient("token")
+	client.APIBase = "http://127.0.0.1:1"
+	client.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	client.Intents()
+	if !strings.Contains(buf.String(), `level=ERROR msg="wit request failed"`) {
+		t.Errorf("transport error was not logged: %s", buf.String())
+	}
+}
+
+func TestLoggingRedactsUserText(t *testing.T) {
+	redact := func(client *wit.Client) { client.RedactUserText = true }
+	body := `{"msg_id":"1","_text":"my card is 4111","outcomes":[{"_text":"my card is 4111","entities":{"number":[{"body":"4111","value":4111}]}}]}`
+	records := logMessage(t, redact, slog.LevelDebug, 200, body)
+	if url := records[0]["url"].(string); !strings.HasSuffix(url, "/message?q=%5BREDACTED%5D&v=20151127") {
+		t.Errorf("url = %s", url)
+	}
+	if strings.Contains(records[1]["body"].(string), "my card") || strings.Contains(records[1]["body"].(string), "4111") {
+		t.Errorf("body = %s", records[1]["body"])
+	}
+}
+
+func TestLoggingRedactsDynamicEntities(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	var buf bytes.Buffer
+	client.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client.RedactUserText = true
+	client.Message(&wit.MessageRequest{
+		Query:    "call Bob",
+		Context:  &wit.Context{Locale: "en_GB"},
+		Entities: wit.DynamicEntities{}.Add("contact", "Robert", "Bob"),
+	})
+	server.AssertQuery(t, "entities", `{"contact":[{"keyword":"Robert","synonyms":["Robert","Bob"]}]}`)
+
+	records := logRecords(t, &buf)
+	url := records[0]["url"].(string)
+	for _, text := range []string{"Bob", "Robert", "en_GB"} {
+		if strings.Contains(url, text) {
+			t.Errorf("%s was logged: %s", text, url)
+		}
+	}
+	if !strings.Contains(url, "entities=%5BREDACTED%5D") || !strings.Contains(url, "context=%5BREDACTED%5D") {
+		t.Errorf("url = %s", url)
+	}
+}
+
+func TestLoggingRedactsEntityValues(t *testing.T) {
+	redact := func(client *wit.Client) { client.RedactUserText = true }
+	body := `{"msg_id":"1","_text":"call alice at 9","outcomes":[{"_text":"call alice at 9","intent":"call","entities":{
+		"contact":[{"body":"alice","value":"Alice","suggested":true},{"body":"bob","suggested":"Bob"}],
+		"datetime":[{"type":"interval","from":{"value":"2015-12-01T09:00:00.000Z","grain":"hour"},"values":[{"value":"2015-12-01T09:00:00.000Z"}]}]}}]}`
+	records := logMessage(t, redact, slog.LevelDebug, 200, body)
+
+	logged := records[1]["body"].(string)
+	for _, text := range []string{"Alice", "Bob", "2015-12-01"} {
+		if strings.Contains(logged, text) {
+			t.Errorf("%s was logged: %s", text, logged)
+		}
+	}
+	for _, kept := range []string{`"intent":"call"`, `"suggested":true`, `"grain":"hour"`, `"type":"interval"`} {
+		if !strings.Contains(logged, kept) {
+			t.Errorf("%s should be kept: %s", kept, logged)
+		}
+	}
+
+	logged = logMessage(t, nil, slog.LevelDebug, 200, body)[1]["body"].(string)
+	if !strings.Contains(logged, "Alice") {
+		t.Errorf("Entity values should only be redacted with RedactUserText: %s", logged)
+	}
+}
+
+func TestLogBody(t *testing.T) {
+	limit := func(limit int) func(*wit.Client) {
+		return func(client *wit.Client) { client.LogBodyLimit = limit }
+	}
+	if body := logMessage(t, limit(4), slog.LevelDebug, 200, `{"msg_id":"1"}`)[1]["body"]; body != `{"ms... (10 bytes truncated)` {
+		t.Errorf("truncated body = %q", body)
+	}
+	if body := logMessage(t, limit(-1), slog.LevelDebug, 200, `{"msg_id":"1"}`)[1]["body"]; body != `{"msg_id":"1"}` {
+		t.Errorf("unlimited body = %q", body)
+	}
+
+	server := wittest.NewServer()
+	defer server.Close()
+	var buf bytes.Buffer
+	client := server.Client()
+	client.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client.AudioMessage(&wit.MessageRequest{FileContents: []byte("RIFF"), ContentType: "audio/wav"})
+	if records := logRecords(t, &buf); len(records) == 0 || records[0]["body"] != "<4 bytes of audio/wav>" {
+		t.Errorf("audio body = %v", records)
+	}
+}
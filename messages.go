@@ -4,6 +4,8 @@
 package wit
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"net/url"
 	"strconv"
@@ -14,6 +16,9 @@ type Message struct {
 	MsgID    string    `json:"msg_id"`
 	Text     string    `json:"_text"`
 	Outcomes []Outcome `json:"outcomes"`
+	// Raw is the JSON the message was parsed from, including any fields this
+	// package does not know about
+	Raw json.RawMessage `json:"-"`
 }
 
 // Outcome represents the outcome portion of a Wit message
@@ -23,6 +28,8 @@ type Outcome struct {
 	IntentId   string                     `json:"intent_id"`
 	Entities   map[string][]MessageEntity `json:"entities"`
 	Confidence float32                    `json:"confidence"`
+	// Raw is the JSON the outcome was parsed from
+	Raw json.RawMessage `json:"-"`
 }
 
 // MessageEntity represents the entity portion of a Wit message
@@ -34,11 +41,14 @@ type MessageEntity struct {
 	Unit     *string              `json:"unit,omitempty"`
 	Body     *string              `json:"body,omitempty"`
 	Entity   *string              `json:"entity,omitempty"`
+	Role     *string              `json:"role,omitempty"`
 	Start    *int64               `json:"start,omitempty"`
 	End      *int64               `json:"end,omitempty"`
 	Values   *[]interface{}       `json:"values,omitempty"`
 	From     *DatetimeIntervalEnd `json:"from,omitempty"`
 	To       *DatetimeIntervalEnd `json:"to,omitempty"`
+	// Raw is the JSON the entity was parsed from
+	Raw json.RawMessage `json:"-"`
 }
 
 // DatetimeValue represents the datetime value portion of a Wit message
@@ -54,33 +64,74 @@ type DatetimeIntervalEnd struct {
 
 // MessageRequest represents a request to process a message
 type MessageRequest struct {
-	File         string `json:"file,omitempty"`
-	Query        string `json:"query"`
-	MsgID        string `json:"msg_id,omitempty"`
-	Context      string `json:"context, omitempty"`
-	ContentType  string `json:"contentType, omitempty"`
-	N            int    `json:"n,omitempty"`
-	FileContents []byte `json:"-"`
-	// Are context and Meta necessary anymore?
-	// Context     Context
-	// Meta        map[string]interface{}
+	File         string          `json:"file,omitempty"`
+	Query        string          `json:"query"`
+	MsgID        string          `json:"msg_id,omitempty"`
+	Context      *Context        `json:"context,omitempty"`
+	ContentType  string          `json:"contentType,omitempty"`
+	N            int             `json:"n,omitempty"`
+	Entities     DynamicEntities `json:"entities,omitempty"`
+	FileContents []byte          `json:"-"`
 }
 
 // Context represents the context portion of the message request
 type Context struct {
-	ReferenceTime string `json:"reference_time"`
-	Timezone      string `json:"timezone"`
+	ReferenceTime string  `json:"reference_time,omitempty"`
+	Timezone      string  `json:"timezone,omitempty"`
+	Locale        string  `json:"locale,omitempty"`
+	Coords        *Coords `json:"coords,omitempty"`
+}
+
+// Coords represents the geographical coordinates of the user within a Context
+type Coords struct {
+	Lat  float64 `json:"lat"`
+	Long float64 `json:"long"`
+}
+
+// DynamicEntities represents keyword lists supplied at request time for entities
+// that are not part of the trained model, keyed by entity name
+type DynamicEntities map[string][]DynamicKeyword
+
+// DynamicKeyword represents a keyword and its synonyms within DynamicEntities
+type DynamicKeyword struct {
+	Keyword  string   `json:"keyword"`
+	Synonyms []string `json:"synonyms"`
+}
+
+// Add appends a keyword and its synonyms to the named entity. The keyword is
+// always included as one of its own synonyms.
+//
+//		entities := wit.DynamicEntities{}
+//		entities.Add("contact", "Alice", "Ali").Add("contact", "Robert", "Bob")
+//		request.Entities = entities
+func (entities DynamicEntities) Add(entity string, keyword string, synonyms ...string) DynamicEntities {
+	dynamicKeyword := DynamicKeyword{Keyword: keyword, Synonyms: []string{keyword}}
+	for _, synonym := range synonyms {
+		if synonym != keyword {
+			dynamicKeyword.Synonyms = append(dynamicKeyword.Synonyms, synonym)
+		}
+	}
+	entities[entity] = append(entities[entity], dynamicKeyword)
+	return entities
 }
 
 // Messages lists an already existing message (https://wit.ai/docs/api#toc_11)
 //
 //		result, err := client.Messages("ba0fcf60-44d3-4499-877e-c8d65c239730")
 func (client *Client) Messages(id string) (*Message, error) {
-	result, err := get(client.APIBase + "/messages/" + id)
+	return client.MessagesWithContext(context.Background(), id)
+}
+
+// MessagesWithContext lists an already existing message, cancelling the
+// request when ctx is done
+//
+//		result, err := client.MessagesWithContext(ctx, "ba0fcf60-44d3-4499-877e-c8d65c239730")
+func (client *Client) MessagesWithContext(ctx context.Context, id string) (*Message, error) {
+	message, err := client.getMessage(ctx, client.APIBase+"/messages/"+id)
 	if err != nil {
 		return nil, err
 	}
-	message, err := parseMessage(result)
+	client.observeMessage(message)
 	return message, nil
 }
 
@@ -88,24 +139,47 @@ func (client *Client) Messages(id string) (*Message, error) {
 //
 //		result, err := client.Message(request)
 func (client *Client) Message(request *MessageRequest) (*Message, error) {
-	query := url.QueryEscape(request.Query)
-	if request.Context != "" {
-		query += "&context=" + request.Context
-	}
-	if request.MsgID != "" {
-		query += "&msg_id" + request.MsgID
+	return client.MessageWithContext(context.Background(), request)
+}
+
+// MessageWithContext requests processing of a text message, cancelling the
+// request when ctx is done
+//
+//		result, err := client.MessageWithContext(ctx, request)
+func (client *Client) MessageWithContext(ctx context.Context, request *MessageRequest) (*Message, error) {
+	if client.Redactor != nil {
+		return client.Redactor.message(ctx, request, client.cachedMessage)
 	}
-	if request.N != 0 {
-		query += "&n=" + strconv.Itoa(request.N)
+	return client.cachedMessage(ctx, request)
+}
+
+// Answers a text message from the MessageCache when there is one, reporting
+// every message returned to the Metrics, cached or not
+func (client *Client) cachedMessage(ctx context.Context, request *MessageRequest) (*Message, error) {
+	var message *Message
+	var err error
+	if client.MessageCache != nil && request.MsgID == "" {
+		message, err = client.MessageCache.message(ctx, request, client.fetchMessage)
+	} else {
+		message, err = client.fetchMessage(ctx, request)
 	}
-	result, err := get(client.APIBase + "/message?q=" + query)
 	if err != nil {
 		return nil, err
 	}
-	message, _ := parseMessage(result)
+	client.observeMessage(message)
 	return message, nil
 }
 
+// Sends a text message to Wit, bypassing any MessageCache
+func (client *Client) fetchMessage(ctx context.Context, request *MessageRequest) (*Message, error) {
+	values, err := messageValues(request)
+	if err != nil {
+		return nil, err
+	}
+	values.Set("q", request.Query)
+	return client.getMessage(ctx, client.APIBase+"/message?"+values.Encode())
+}
+
 // AudioMessage requests processing of an audio message (https://wit.ai/docs/api#toc_8)
 //
 // 		request := &MessageRequest{}
@@ -114,25 +188,274 @@ func (client *Client) Message(request *MessageRequest) (*Message, error) {
 //		request.ContentType = "audio/wav;rate=8000"
 // 		message, err := client.AudioMessage(request)
 func (client *Client) AudioMessage(request *MessageRequest) (*Message, error) {
-	result, err := postFile(client.APIBase+"/speech", request)
+	return client.AudioMessageWithContext(context.Background(), request)
+}
+
+// AudioMessageWithContext requests processing of an audio message, cancelling
+// the request when ctx is done
+//
+//		message, err := client.AudioMessageWithContext(ctx, request)
+func (client *Client) AudioMessageWithContext(ctx context.Context, request *MessageRequest) (*Message, error) {
+	values, err := messageValues(request)
 	if err != nil {
 		return nil, err
 	}
-	message, err := parseMessage(result)
+	resource := client.APIBase + "/speech"
+	if len(values) > 0 {
+		resource += "?" + values.Encode()
+	}
+	message, err := client.postFile(ctx, resource, request)
 	if err != nil {
 		return nil, err
 	}
+	client.observeMessage(message)
 	return message, nil
 }
 
+// Builds the query parameters shared by the message and speech endpoints,
+// JSON encoding the context and dynamic entities when they are given
+//
+//		values, err := messageValues(request)
+func messageValues(request *MessageRequest) (url.Values, error) {
+	values := url.Values{}
+	if request.Context != nil {
+		data, err := json.Marshal(request.Context)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("context", string(data))
+	}
+	if request.MsgID != "" {
+		values.Set("msg_id", request.MsgID)
+	}
+	if request.N != 0 {
+		values.Set("n", strconv.Itoa(request.N))
+	}
+	if len(request.Entities) > 0 {
+		data, err := json.Marshal(request.Entities)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("entities", string(data))
+	}
+	return values, nil
+}
+
 // Parses the JSON into a Message
 //
-//		message, err := parseMessage([]byte(data))
-func parseMessage(data []byte) (*Message, error) {
+//		message, err := client.parseMessage([]byte(data))
+func (client *Client) parseMessage(data []byte) (*Message, error) {
 	message := &Message{}
-	err := json.Unmarshal(data, message)
+	err := client.decode(data, message)
 	if err != nil {
 		return nil, err
 	}
+	setMessageRaw(message, data)
 	return message, nil
 }
+
+// Keeps the JSON of a parsed message, of its outcomes and of their entities
+func setMessageRaw(message *Message, data []byte) {
+	message.Raw = data
+	raw := struct {
+		Outcomes []json.RawMessage `json:"outcomes"`
+	}{}
+	if json.Unmarshal(data, &raw) != nil || len(raw.Outcomes) != len(message.Outcomes) {
+		return
+	}
+	for i, rawOutcome := range raw.Outcomes {
+		outcome := &message.Outcomes[i]
+		outcome.Raw = rawOutcome
+		rawEntities := struct {
+			Entities map[string][]json.RawMessage `json:"entities"`
+		}{}
+		if json.Unmarshal(rawOutcome, &rawEntities) != nil {
+			continue
+		}
+		for name, values := range rawEntities.Entities {
+			if len(values) != len(outcome.Entities[name]) {
+				continue
+			}
+			for j, value := range values {
+				outcome.Entities[name][j].Raw = value
+			}
+		}
+	}
+}
+
+// Returns a deep copy of the message, so that a cached message can be handed
+// out without callers modifying the cache
+func (message *Message) copy() *Message {
+	copied := *message
+	copied.Raw = copyBytes(message.Raw)
+	if message.Outcomes == nil {
+		return &copied
+	}
+	copied.Outcomes = make([]Outcome, len(message.Outcomes))
+	for i, outcome := range message.Outcomes {
+		outcome.Raw = copyBytes(outcome.Raw)
+		if outcome.Entities != nil {
+			entities := make(map[string][]MessageEntity, len(outcome.Entities))
+			for name, values := range outcome.Entities {
+				if values == nil {
+					entities[name] = nil
+					continue
+				}
+				entities[name] = make([]MessageEntity, len(values))
+				for j, entity := range values {
+					entities[name][j] = entity.copy()
+				}
+			}
+			outcome.Entities = entities
+		}
+		copied.Outcomes[i] = outcome
+	}
+	return &copied
+}
+
+// Returns a deep copy of the entity
+func (entity MessageEntity) copy() MessageEntity {
+	entity.Metadata = copyPointer(entity.Metadata)
+	entity.Grain = copyPointer(entity.Grain)
+	entity.Type = copyPointer(entity.Type)
+	entity.Unit = copyPointer(entity.Unit)
+	entity.Body = copyPointer(entity.Body)
+	entity.Entity = copyPointer(entity.Entity)
+	entity.Role = copyPointer(entity.Role)
+	entity.Start = copyPointer(entity.Start)
+	entity.End = copyPointer(entity.End)
+	entity.From = copyPointer(entity.From)
+	entity.To = copyPointer(entity.To)
+	if entity.Value != nil {
+		value := copyJSONValue(*entity.Value)
+		entity.Value = &value
+	}
+	if entity.Values != nil {
+		values := make([]interface{}, len(*entity.Values))
+		for i, value := range *entity.Values {
+			values[i] = copyJSONValue(value)
+		}
+		entity.Values = &values
+	}
+	entity.Raw = copyBytes(entity.Raw)
+	return entity
+}
+
+// Returns a copy of the message for another text: the texts equal to from are
+// set to to, and entity maps the span of each entity that has one onto to.
+// Raw is rebuilt to match, with its keys sorted. It reports false when entity
+// cannot map a span.
+func (message *Message) rewrite(from string, to string, entity func(*MessageEntity) bool) (*Message, bool) {
+	rewritten := message.copy()
+	if rewritten.Text == from {
+		rewritten.Text = to
+	}
+	for i := range rewritten.Outcomes {
+		outcome := &rewritten.Outcomes[i]
+		if outcome.Text == from {
+			outcome.Text = to
+		}
+		for _, values := range outcome.Entities {
+			for j := range values {
+				if values[j].Start != nil && values[j].End != nil && !entity(&values[j]) {
+					return nil, false
+				}
+			}
+		}
+	}
+	if rewritten.Raw != nil {
+		rewritten.rewriteRaw(from, to)
+	}
+	return rewritten, true
+}
+
+// Applies the changes made by rewrite to Raw, keeping the fields this package
+// does not know about. Raw is dropped if it cannot be decoded.
+func (message *Message) rewriteRaw(from string, to string) {
+	decoder := json.NewDecoder(bytes.NewReader(message.Raw))
+	decoder.UseNumber()
+	raw := map[string]interface{}{}
+	if decoder.Decode(&raw) != nil {
+		message.Raw = nil
+		return
+	}
+	if raw["_text"] == from {
+		raw["_text"] = to
+	}
+	outcomes, _ := raw["outcomes"].([]interface{})
+	for i, item := range outcomes {
+		rawOutcome, ok := item.(map[string]interface{})
+		if !ok || i >= len(message.Outcomes) {
+			continue
+		}
+		if rawOutcome["_text"] == from {
+			rawOutcome["_text"] = to
+		}
+		rawEntities, _ := rawOutcome["entities"].(map[string]interface{})
+		for name, values := range message.Outcomes[i].Entities {
+			rawValues, _ := rawEntities[name].([]interface{})
+			if len(rawValues) != len(values) {
+				continue
+			}
+			for j, entity := range values {
+				if rawEntity, ok := rawValues[j].(map[string]interface{}); ok {
+					setRawField(rawEntity, "start", entity.Start)
+					setRawField(rawEntity, "end", entity.End)
+					setRawField(rawEntity, "body", entity.Body)
+					if entity.Value != nil {
+						if value, isString := (*entity.Value).(string); isString {
+							setRawField(rawEntity, "value", &value)
+						}
+					}
+				}
+			}
+		}
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		message.Raw = nil
+		return
+	}
+	setMessageRaw(message, data)
+}
+
+// Sets a field of a decoded JSON object, if the object has it
+func setRawField[T any](object map[string]interface{}, key string, value *T) {
+	if _, found := object[key]; found && value != nil {
+		object[key] = *value
+	}
+}
+
+func copyPointer[T any](pointer *T) *T {
+	if pointer == nil {
+		return nil
+	}
+	copied := *pointer
+	return &copied
+}
+
+func copyBytes(data json.RawMessage) json.RawMessage {
+	if data == nil {
+		return nil
+	}
+	return append(json.RawMessage(nil), data...)
+}
+
+// Copies the maps and slices of a decoded JSON value
+func copyJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyJSONValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyJSONValue(item)
+		}
+		return copied
+	}
+	return value
+}
@@ -1,107 +1,46 @@
 // Copyright (c) 2014 Jason Goecke
 // messages_test.go
 
-package wit
+package wit_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
-	"time"
-)
-
-var msgID string
-
-func TestWitMessageParsing(t *testing.T) {
-	data := `
-	{
-	  "msg_id" : "2f41839e-2b54-4de2-aa59-fc016c3e58d1",
-	  "_text" : "how many people between Tuesday and Friday?",
-	  "outcomes" : [ {
-	    "_text" : "how many people between Tuesday and Friday?",
-	    "confidence" : 0.522,
-	    "intent" : "query_metrics",
-	    "entities" : {
-	      "datetime" : [ {
-	        "type" : "interval",
-	        "from" : {
-	          "value" : "2015-12-01T00:00:00.000-08:00",
-	          "grain" : "day"
-	        },
-	        "to" : {
-	          "value" : "2015-12-05T00:00:00.000-08:00",
-	          "grain" : "day"
-	        },
-	        "values" : [ {
-	          "type" : "interval",
-	          "from" : {
-	            "value" : "2015-12-01T00:00:00.000-08:00",
-	            "grain" : "day"
-	          },
-	          "to" : {
-	            "value" : "2015-12-05T00:00:00.000-08:00",
-	            "grain" : "day"
-	          }
-	        }, {
-	          "type" : "interval",
-	          "from" : {
-	            "value" : "2015-12-08T00:00:00.000-08:00",
-	            "grain" : "day"
-	          },
-	          "to" : {
-	            "value" : "2015-12-12T00:00:00.000-08:00",
-	            "grain" : "day"
-	          }
-	        }, {
-	          "type" : "interval",
-	          "from" : {
-	            "value" : "2015-12-15T00:00:00.000-08:00",
-	            "grain" : "day"
-	          },
-	          "to" : {
-	            "value" : "2015-12-19T00:00:00.000-08:00",
-	            "grain" : "day"
-	          }
-	        } ]
-	      } ]
-	    }
-	  } ]
-	}`
-
-	message, err := parseMessage([]byte(data))
-	if err != nil {
-		t.Error(err.Error())
-	}
 
-	if message.MsgID != "2f41839e-2b54-4de2-aa59-fc016c3e58d1" {
-		t.Errorf("not equal %s != %s", "2f41839e-2b54-4de2-aa59-fc016c3e58d1", message.MsgID)
-	}
-	if message.Outcomes[0].Intent != "query_metrics" {
-		t.Errorf("not equal %s != %s", "query_metrics", message.Outcomes[0].Intent)
-	}
-	if message.Outcomes[0].Entities["datetime"][0].From.Grain != "day" {
-		t.Errorf("not equal %s != %s", "day", message.Outcomes[0].Entities["datetime"][0].From.Grain)
-	}
-}
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
 
 func TestWitMessageRequest(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddMessage("Hello world", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting", Confidence: 0.9}}})
+	client := server.Client()
 
-	request := &MessageRequest{}
+	request := &wit.MessageRequest{}
 	request.Query = "Hello world"
 	result, err := client.Message(request)
 	if err != nil {
 		t.Error(err)
 		return
 	}
-	if result.Text != "Hello world" {
+	if result.Text != "Hello world" || result.Outcomes[0].Intent != "greeting" {
 		t.Error("Did not process properly")
 	}
-	msgID = result.MsgID
 }
 
 func TestWitPostAudioMessage(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	request := &MessageRequest{}
+	server := wittest.NewServer()
+	defer server.Close()
+	server.SetSpeech(&wit.Message{Text: "hello world"})
+	client := server.Client()
+
+	request := &wit.MessageRequest{}
 	request.File = "./audio_sample/helloWorld.wav"
 	request.ContentType = "audio/wav"
 	message, err := client.AudioMessage(request)
@@ -112,24 +51,24 @@ func TestWitPostAudioMessage(t *testing.T) {
 			t.Error("Audio POST did not work properly")
 		}
 	}
+	speech := server.AssertRequested(t, "POST", "/speech")
+	if len(speech.Body) == 0 {
+		t.Error("Audio file was not uploaded")
+	}
 }
 
 func TestWitPostAudioContentsMessage(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	file, err := os.Open("./audio_sample/helloWorld.wav")
+	server := wittest.NewServer()
+	defer server.Close()
+	server.SetSpeech(&wit.Message{Text: "hello world"})
+	client := server.Client()
+
+	data, err := os.ReadFile("./audio_sample/helloWorld.wav")
 	if err != nil {
 		t.Error(err)
 		return
 	}
-	defer file.Close()
-	stats, statsErr := file.Stat()
-	if statsErr != nil {
-		t.Error(err)
-	}
-	size := stats.Size()
-	data := make([]byte, size)
-	file.Read(data)
-	request := &MessageRequest{}
+	request := &wit.MessageRequest{}
 	request.FileContents = data
 	request.ContentType = "audio/wav"
 	message, err := client.AudioMessage(request)
@@ -140,18 +79,184 @@ func TestWitPostAudioContentsMessage(t *testing.T) {
 			t.Error("Audio POST did not work properly")
 		}
 	}
+	speech := server.AssertRequested(t, "POST", "/speech")
+	if !bytes.Equal(speech.Body, data) {
+		t.Error("Audio contents were not uploaded properly")
+	}
 }
 
 func TestWitMessages(t *testing.T) {
-	client := NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
-	//Wait for the message to be indexed
-	time.Sleep(300 * time.Millisecond)
-	message, err := client.Messages(msgID)
+	server := wittest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	sent, err := client.Message(&wit.MessageRequest{Query: "Hello world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := client.Messages(sent.MsgID)
 	if err != nil {
 		t.Error(err)
 	} else {
-		if message.MsgID != msgID {
+		if message.MsgID != sent.MsgID || message.Text != "Hello world" {
 			t.Error("Message JSON did not parse properly.")
 		}
 	}
+	if _, err = client.Messages("unknown"); err == nil || err.Error() != http.StatusText(404) {
+		t.Error("Should have returned a not found error")
+	}
+}
+
+func TestWitMessageQuery(t *testing.T) {
+	context := &wit.Context{
+		ReferenceTime: "2015-12-01T10:00:00.000-08:00",
+		Timezone:      "America/Los_Angeles",
+		Locale:        "en_US",
+		Coords:        &wit.Coords{Lat: 37.47, Long: -122.14},
+	}
+	contextJSON := `{"reference_time":"2015-12-01T10:00:00.000-08:00","timezone":"America/Los_Angeles","locale":"en_US","coords":{"lat":37.47,"long":-122.14}}`
+
+	tests := []struct {
+		name     string
+		request  *wit.MessageRequest
+		expected url.Values
+	}{
+		{
+			"query only",
+			&wit.MessageRequest{Query: "Hello world"},
+			url.Values{"q": {"Hello world"}},
+		},
+		{
+			"query with reserved characters",
+			&wit.MessageRequest{Query: "what's 1+1 & 2=2? 100%"},
+			url.Values{"q": {"what's 1+1 & 2=2? 100%"}},
+		},
+		{
+			"context",
+			&wit.MessageRequest{Query: "Hello", Context: context},
+			url.Values{"q": {"Hello"}, "context": {contextJSON}},
+		},
+		{
+			"partial context",
+			&wit.MessageRequest{Query: "Hello", Context: &wit.Context{Timezone: "Europe/Paris"}},
+			url.Values{"q": {"Hello"}, "context": {`{"timezone":"Europe/Paris"}`}},
+		},
+		{
+			"msg_id",
+			&wit.MessageRequest{Query: "Hello", MsgID: "abc-123"},
+			url.Values{"q": {"Hello"}, "msg_id": {"abc-123"}},
+		},
+		{
+			"n",
+			&wit.MessageRequest{Query: "Hello", N: 3},
+			url.Values{"q": {"Hello"}, "n": {"3"}},
+		},
+		{
+			"dynamic entities",
+			&wit.MessageRequest{Query: "Call Ali", Entities: wit.DynamicEntities{}.Add("contact", "Alice", "Ali")},
+			url.Values{"q": {"Call Ali"}, "entities": {`{"contact":[{"keyword":"Alice","synonyms":["Alice","Ali"]}]}`}},
+		},
+		{
+			"all parameters",
+			&wit.MessageRequest{Query: "Hello", Context: context, MsgID: "abc-123", N: 2},
+			url.Values{"q": {"Hello"}, "context": {contextJSON}, "msg_id": {"abc-123"}, "n": {"2"}},
+		},
+	}
+
+	server := wittest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	for _, test := range tests {
+		_, err := client.Message(test.request)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		received, _ := server.LastRequest()
+		if received.Path != "/message" {
+			t.Errorf("%s: unexpected path %s", test.name, received.Path)
+		}
+		test.expected.Set("v", strings.TrimPrefix(wit.APIVersion, "v="))
+		if received.Query.Encode() != test.expected.Encode() {
+			t.Errorf("%s: not equal %s != %s", test.name, test.expected.Encode(), received.Query.Encode())
+		}
+	}
+}
+
+func TestDynamicEntitiesAdd(t *testing.T) {
+	entities := wit.DynamicEntities{}
+	entities.Add("contact", "Alice", "Ali", "Alice").Add("contact", "Robert", "Bob")
+	entities.Add("playlist", "Chill")
+
+	if len(entities["contact"]) != 2 {
+		t.Fatalf("not equal %d != %d", 2, len(entities["contact"]))
+	}
+	alice := entities["contact"][0]
+	if alice.Keyword != "Alice" || len(alice.Synonyms) != 2 || alice.Synonyms[0] != "Alice" || alice.Synonyms[1] != "Ali" {
+		t.Errorf("Did not add Alice properly: %v", alice)
+	}
+	if entities["contact"][1].Synonyms[1] != "Bob" {
+		t.Errorf("not equal %s != %s", "Bob", entities["contact"][1].Synonyms[1])
+	}
+	if len(entities["playlist"][0].Synonyms) != 1 {
+		t.Error("Keyword should be its own synonym")
+	}
+}
+
+func TestWitAudioMessageQuery(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.SetSpeech(&wit.Message{Text: "call ali"})
+	client := server.Client()
+
+	request := &wit.MessageRequest{}
+	request.FileContents = []byte("RIFF")
+	request.ContentType = "audio/wav"
+	request.Entities = wit.DynamicEntities{}.Add("contact", "Alice", "Ali")
+	_, err := client.AudioMessage(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	speech := server.AssertRequested(t, "POST", "/speech")
+	if speech.Query.Get("entities") != `{"contact":[{"keyword":"Alice","synonyms":["Alice","Ali"]}]}` {
+		t.Errorf("Dynamic entities not sent, got %s", speech.Query.Get("entities"))
+	}
+	if speech.Query.Get("q") != "" {
+		t.Error("Speech requests should not send a query")
+	}
+	if speech.Header.Get("Content-Type") != "audio/wav" {
+		t.Errorf("not equal %s != %s", "audio/wav", speech.Header.Get("Content-Type"))
+	}
+}
+
+func TestMessageRaw(t *testing.T) {
+	data := `{"msg_id":"1","_text":"9h","sentiment":"neutral","outcomes":[{"_text":"9h","intent":"alarm","confidence":0.5,"domain":"time",
+		"entities":{"datetime":[{"value":"2015-01-01T09:00:00.000Z","start":0,"end":2,"timezone":"UTC"}]}}]}`
+	server := wittest.NewServer()
+	defer server.Close()
+	server.Script("GET", "/message", 200, data)
+
+	message, err := server.Client().Message(&wit.MessageRequest{Query: "9h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := struct {
+		Sentiment string `json:"sentiment"`
+	}{}
+	if err = json.Unmarshal(message.Raw, &extra); err != nil || extra.Sentiment != "neutral" {
+		t.Errorf("Unexpected message extra %v, %v", extra, err)
+	}
+	outcome := message.Outcomes[0]
+	if !strings.Contains(string(outcome.Raw), `"domain":"time"`) || strings.Contains(string(outcome.Raw), "sentiment") {
+		t.Errorf("Unexpected outcome raw %s", outcome.Raw)
+	}
+	entity := outcome.Entities["datetime"][0]
+	if string(entity.Raw) != `{"value":"2015-01-01T09:00:00.000Z","start":0,"end":2,"timezone":"UTC"}` {
+		t.Errorf("Unexpected entity raw %s", entity.Raw)
+	}
+	encoded, _ := json.Marshal(message)
+	if strings.Contains(string(encoded), "sentiment") {
+		t.Errorf("Raw should not be marshalled, got %s", encoded)
+	}
 }
@@ -0,0 +1,89 @@
+// Copyright (c) 2014 Jason Goecke
+// metrics.go
+
+package wit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Metrics receives measurements of the requests a Client makes and of the
+// messages it returns, including those answered by a MessageCache.
+// Implementations must be safe for concurrent use.
+//
+//		metrics := wit.NewPrometheusMetrics()
+//		client.Metrics = metrics
+//		http.Handle("/metrics", metrics)
+type Metrics interface {
+	ObserveRequest(observation *RequestObservation)
+	ObserveMessage(message *Message)
+}
+
+// RequestObservation represents one completed request to the Wit API
+type RequestObservation struct {
+	// Endpoint is the request path with identifiers replaced, e.g. /entities/{id}
+	Endpoint string
+	Verb     string
+	// StatusCode is zero when no response was received
+	StatusCode int
+	// ErrorClass is empty on success, otherwise one of the ErrorClass constants
+	ErrorClass    string
+	Duration      time.Duration
+	ResponseBytes int
+}
+
+// The error classes of a RequestObservation
+const (
+	ErrorClassCanceled    = "canceled"
+	ErrorClassTimeout     = "timeout"
+	ErrorClassNetwork     = "network"
+	ErrorClassRateLimited = "rate_limited"
+	ErrorClassClient      = "client_error"
+	ErrorClassServer      = "server_error"
+)
+
+// Classifies a failed request by its transport error, such as a response
+// body cut short, or else by its status code
+func errorClass(statusCode int, err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case err != nil:
+		return ErrorClassNetwork
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case statusCode >= 500:
+		return ErrorClassServer
+	case statusCode != http.StatusOK:
+		return ErrorClassClient
+	}
+	return ""
+}
+
+// Names the endpoint of a resource URL, replacing the entity, value,
+// expression and message identifiers so they do not explode label counts
+//
+//		endpoint("https://api.wit.ai/entities/city/values/Paris") // "/entities/{id}/values/{value}"
+func endpoint(resource string) string {
+	path := resource
+	if parsed, err := url.Parse(resource); err == nil {
+		path = parsed.Path
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 0 && (segments[0] == "entities" || segments[0] == "messages") {
+		placeholders := map[int]string{1: "{id}", 3: "{value}", 5: "{expression}"}
+		for i := range segments {
+			if placeholder, found := placeholders[i]; found {
+				segments[i] = placeholder
+			}
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
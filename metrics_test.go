@@ -0,0 +1,116 @@
+// Copyright (c) 2014 Jason Goecke
+// metrics_test.go
+
+package wit_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func TestEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"https://api.wit.ai/message?q=hello":                          "/message",
+		"https://api.wit.ai/speech":                                   "/speech",
+		"https://api.wit.ai/intents":                                  "/intents",
+		"https://api.wit.ai/entities":                                 "/entities",
+		"https://api.wit.ai/entities/city":                            "/entities/{id}",
+		"https://api.wit.ai/entities/city/values/Paris":               "/entities/{id}/values/{value}",
+		"https://api.wit.ai/entities/city/values/Paris/expressions/x": "/entities/{id}/values/{value}/expressions/{expression}",
+		"https://api.wit.ai/messages/ba0fcf60":                        "/messages/{id}",
+	}
+	for resource, expected := range tests {
+		if actual := wit.Endpoint(resource); actual != expected {
+			t.Errorf("endpoint(%q) = %q, want %q", resource, actual, expected)
+		}
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		status   int
+		err      error
+		expected string
+	}{
+		{200, nil, ""},
+		{0, errors.New("connection refused"), wit.ErrorClassNetwork},
+		{0, context.Canceled, wit.ErrorClassCanceled},
+		{0, context.DeadlineExceeded, wit.ErrorClassTimeout},
+		{429, nil, wit.ErrorClassRateLimited},
+		{404, nil, wit.ErrorClassClient},
+		{503, nil, wit.ErrorClassServer},
+		{200, io.ErrUnexpectedEOF, wit.ErrorClassNetwork},
+		{503, errors.New("connection reset by peer"), wit.ErrorClassNetwork},
+	}
+	for _, test := range tests {
+		if actual := wit.ErrorClass(test.status, test.err); actual != test.expected {
+			t.Errorf("errorClass(%d, %v) = %q, want %q", test.status, test.err, actual, test.expected)
+		}
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	for i := 0; i < 2; i++ {
+		server.Script("GET", "/message", 200, `{"msg_id":"1","_text":"hi","outcomes":[{"intent":"greeting","confidence":0.85}]}`)
+	}
+	metrics := wit.NewPrometheusMetrics()
+	client := server.Client()
+	client.Metrics = metrics
+
+	client.Message(&wit.MessageRequest{Query: "hi"})
+	client.Message(&wit.MessageRequest{Query: "hi again"})
+	client.Entity("missing")
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	out := recorder.Body.String()
+	expected := []string{
+		"# TYPE wit_requests_total counter",
+		`wit_requests_total{endpoint="/message",verb="GET",status="200"} 2`,
+		`wit_requests_total{endpoint="/entities/{id}",verb="GET",status="404"} 1`,
+		`wit_request_errors_total{endpoint="/entities/{id}",verb="GET",class="client_error"} 1`,
+		"# TYPE wit_request_duration_seconds histogram",
+		`wit_request_duration_seconds_count{endpoint="/message",verb="GET"} 2`,
+		`wit_response_size_bytes_bucket{endpoint="/message",verb="GET",le="256"} 2`,
+		`wit_response_size_bytes_sum{endpoint="/message",verb="GET"} 160`,
+		`wit_message_intents_total{intent="greeting"} 2`,
+		`wit_message_confidence_bucket{intent="greeting",le="0.8"} 0`,
+		`wit_message_confidence_bucket{intent="greeting",le="0.9"} 2`,
+		`wit_message_confidence_bucket{intent="greeting",le="+Inf"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+	if recorder.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", recorder.Header().Get("Content-Type"))
+	}
+}
+
+func TestPrometheusMetricsNetworkError(t *testing.T) {
+	metrics := wit.NewPrometheusMetrics()
+	client := wit.NewClient("token")
+	client.APIBase = "http://127.0.0.1:1"
+	client.Metrics = metrics
+	client.Intents()
+
+	var out strings.Builder
+	metrics.WriteTo(&out)
+	if !strings.Contains(out.String(), `wit_requests_total{endpoint="/intents",verb="GET",status="error"} 1`) ||
+		!strings.Contains(out.String(), `class="network"`) {
+		t.Errorf("unexpected metrics:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "wit_response_size_bytes") {
+		t.Error("a failed request should not observe a response size")
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2014 Jason Goecke
+// pagination.go
+
+package wit
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// DefaultPageSize is the number of items fetched per page when
+// ListOptions.Limit is zero
+const DefaultPageSize = 100
+
+// Done is returned by Iterator.Next once every item has been returned
+var Done = errors.New("wit: no more items")
+
+// ListOptions configures a paginated list. Limit is the page size and Offset
+// the number of items to skip before the first page.
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+// Iterator walks a list page by page, fetching the next page when the
+// current one is used up
+//
+//	it := client.ListEntities(nil)
+//	for {
+//		id, err := it.Next(ctx)
+//		if err == wit.Done {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+type Iterator[T any] struct {
+	fetch  func(ctx context.Context, limit int, offset int) ([]T, error)
+	limit  int
+	offset int
+	page   []T
+	first  *T
+	done   bool
+	err    error
+}
+
+func newIterator[T any](options *ListOptions, fetch func(ctx context.Context, limit int, offset int) ([]T, error)) *Iterator[T] {
+	it := &Iterator[T]{fetch: fetch, limit: DefaultPageSize}
+	if options != nil {
+		if options.Limit > 0 {
+			it.limit = options.Limit
+		}
+		it.offset = options.Offset
+	}
+	return it
+}
+
+// Next returns the next item, Done after the last one, or the error that
+// stopped the iteration
+func (it *Iterator[T]) Next(ctx context.Context) (T, error) {
+	var zero T
+	for len(it.page) == 0 {
+		if it.err != nil {
+			return zero, it.err
+		}
+		if it.done {
+			return zero, Done
+		}
+		if err := it.nextPage(ctx); err != nil {
+			it.err = err
+			return zero, err
+		}
+	}
+	item := it.page[0]
+	it.page = it.page[1:]
+	return item, nil
+}
+
+// All returns the remaining items as a sequence for range loops, ending after
+// the first error
+//
+//	for id, err := range client.ListEntities(nil).All(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (it *Iterator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			item, err := it.Next(ctx)
+			if err == Done {
+				return
+			}
+			if !yield(item, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Collect returns the remaining items as a slice
+//
+//		ids, err := client.ListEntities(nil).Collect(ctx)
+func (it *Iterator[T]) Collect(ctx context.Context) ([]T, error) {
+	var items []T
+	for item, err := range it.All(ctx) {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Fetches a page. A short page is the last one. Endpoints that ignore limit
+// and offset return everything at once: a page longer than the limit is cut
+// to start at the offset and ends the iteration, as does a page starting with
+// the same item as the previous page. An endpoint ignoring them that returns
+// no more than the limit cannot be told apart, so its items are not skipped.
+func (it *Iterator[T]) nextPage(ctx context.Context) error {
+	page, err := it.fetch(ctx, it.limit, it.offset)
+	if err != nil {
+		return err
+	}
+	if len(page) > it.limit {
+		it.done = true
+		it.page = page[min(it.offset, len(page)):]
+		return nil
+	}
+	if len(page) > 0 && it.first != nil && reflect.DeepEqual(page[0], *it.first) {
+		it.done = true
+		return nil
+	}
+	if len(page) > 0 {
+		it.first = &page[0]
+	}
+	it.done = len(page) != it.limit
+	it.offset += len(page)
+	it.page = page
+	return nil
+}
+
+// ListEntities iterates over the ids of the configured entities, a page at a time
+//
+//		ids, err := client.ListEntities(&wit.ListOptions{Limit: 500}).Collect(ctx)
+func (client *Client) ListEntities(options *ListOptions) *Iterator[string] {
+	return newIterator(options, func(ctx context.Context, limit int, offset int) ([]string, error) {
+		result, err := client.get(ctx, client.APIBase+"/entities?"+pageValues(limit, offset))
+		if err != nil {
+			return nil, err
+		}
+		entities, err := client.parseEntities(result)
+		if err != nil {
+			return nil, err
+		}
+		return *entities, nil
+	})
+}
+
+// ListIntents iterates over the configured intents, a page at a time
+//
+//		for intent, err := range client.ListIntents(nil).All(ctx) {
+//			...
+//		}
+func (client *Client) ListIntents(options *ListOptions) *Iterator[Intent] {
+	return newIterator(options, func(ctx context.Context, limit int, offset int) ([]Intent, error) {
+		result, err := client.get(ctx, client.APIBase+"/intents?"+pageValues(limit, offset))
+		if err != nil {
+			return nil, err
+		}
+		intents, err := client.parseIntents(result)
+		if err != nil {
+			return nil, err
+		}
+		return *intents, nil
+	})
+}
+
+// ListApps iterates over the apps the access token can see, a page at a time
+//
+//		apps, err := client.ListApps(&wit.ListOptions{Limit: 50}).Collect(ctx)
+func (client *Client) ListApps(options *ListOptions) *Iterator[App] {
+	return newIterator(options, func(ctx context.Context, limit int, offset int) ([]App, error) {
+		result, err := client.get(ctx, client.APIBase+"/apps?"+pageValues(limit, offset))
+		if err != nil {
+			return nil, err
+		}
+		apps, err := client.parseApps(result)
+		if err != nil {
+			return nil, err
+		}
+		return *apps, nil
+	})
+}
+
+// ListUtterances iterates over the utterances the app was trained with, a
+// page at a time
+//
+//		for utterance, err := range client.ListUtterances(&wit.ListOptions{Limit: 500}).All(ctx) {
+//			...
+//		}
+func (client *Client) ListUtterances(options *ListOptions) *Iterator[Utterance] {
+	return newIterator(options, func(ctx context.Context, limit int, offset int) ([]Utterance, error) {
+		result, err := client.get(ctx, client.APIBase+"/utterances?"+pageValues(limit, offset))
+		if err != nil {
+			return nil, err
+		}
+		utterances, err := client.parseUtterances(result)
+		if err != nil {
+			return nil, err
+		}
+		return *utterances, nil
+	})
+}
+
+// ListEntityValues iterates over the values of an entity. The API returns the
+// values within the entity, so they are fetched once and handed out in pages.
+//
+//		values, err := client.ListEntityValues("favorite_city", nil).Collect(ctx)
+func (client *Client) ListEntityValues(id string, options *ListOptions) *Iterator[EntityValue] {
+	var values []EntityValue
+	fetched := false
+	return newIterator(options, func(ctx context.Context, limit int, offset int) ([]EntityValue, error) {
+		if !fetched {
+			result, err := client.get(ctx, client.APIBase+"/entities/"+url.QueryEscape(id))
+			if err != nil {
+				return nil, err
+			}
+			entity, err := client.parseEntity(result)
+			if err != nil {
+				return nil, err
+			}
+			values, fetched = entity.Values, true
+		}
+		if offset >= len(values) {
+			return nil, nil
+		}
+		end := offset + limit
+		if end > len(values) {
+			end = len(values)
+		}
+		return values[offset:end], nil
+	})
+}
+
+func pageValues(limit int, offset int) string {
+	values := url.Values{}
+	values.Set("limit", strconv.Itoa(limit))
+	values.Set("offset", strconv.Itoa(offset))
+	return values.Encode()
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2014 Jason Goecke
+// pagination_test.go
+
+package wit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+// Stores entities entity-0 ... entity-(total-1) in a fake Wit API
+func addEntities(server *wittest.Server, total int) {
+	for i := 0; i < total; i++ {
+		server.AddEntity(&wit.Entity{ID: "entity-" + strconv.Itoa(i)})
+	}
+}
+
+// Makes a fake Wit API ignore limit and offset when listing entities
+func ignorePagination(server *wittest.Server) {
+	server.Intercept("GET", "/entities", func(w http.ResponseWriter, r *http.Request) {
+		r.URL.RawQuery = ""
+	})
+}
+
+// Returns the query strings of the requests to the list endpoint at path
+func listQueries(server *wittest.Server, path string) []string {
+	var queries []string
+	for _, request := range server.Requested("GET", path) {
+		queries = append(queries, request.Query.Encode())
+	}
+	return queries
+}
+
+func TestIteratorPages(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addEntities(server, 5)
+	it := client.ListEntities(&wit.ListOptions{Limit: 2})
+	var ids []string
+	for {
+		id, err := it.Next(context.Background())
+		if err == wit.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if strings.Join(ids, ",") != "entity-0,entity-1,entity-2,entity-3,entity-4" {
+		t.Errorf("ids = %v", ids)
+	}
+	expected := "limit=2&offset=0&v=20151127|limit=2&offset=2&v=20151127|limit=2&offset=4&v=20151127"
+	if queries := listQueries(server, "/entities"); strings.Join(queries, "|") != expected {
+		t.Errorf("queries = %v", queries)
+	}
+	if _, err := it.Next(context.Background()); err != wit.Done {
+		t.Errorf("Next after the end = %v", err)
+	}
+}
+
+func TestIteratorExactPages(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addEntities(server, 4)
+	ids, err := client.ListEntities(&wit.ListOptions{Limit: 2, Offset: 1}).Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queries := listQueries(server, "/entities"); strings.Join(ids, ",") != "entity-1,entity-2,entity-3" || len(queries) != 2 {
+		t.Errorf("ids = %v after %v", ids, queries)
+	}
+}
+
+func TestIteratorIgnoredPagination(t *testing.T) {
+	for _, total := range []int{3, 2, 1} {
+		server, client := wittest.NewTestServer(t)
+		addEntities(server, total)
+		ignorePagination(server)
+		ids, err := client.ListEntities(&wit.ListOptions{Limit: 2}).Collect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if queries := listQueries(server, "/entities"); len(ids) != total || len(queries) > 2 {
+			t.Errorf("total %d: ids = %v after %d requests", total, ids, len(queries))
+		}
+	}
+}
+
+func TestIteratorIgnoredPaginationOffset(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addEntities(server, 5)
+	ignorePagination(server)
+	ids, err := client.ListEntities(&wit.ListOptions{Limit: 2, Offset: 3}).Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queries := listQueries(server, "/entities"); strings.Join(ids, ",") != "entity-3,entity-4" || len(queries) != 1 {
+		t.Errorf("ids = %v after %v", ids, queries)
+	}
+
+	ids, err = client.ListEntities(&wit.ListOptions{Limit: 2, Offset: 9}).Collect(context.Background())
+	if err != nil || len(ids) != 0 {
+		t.Errorf("ids = %v, %v", ids, err)
+	}
+}
+
+func TestIteratorAllStopsOnError(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.Script("GET", "/intents", 200, `[{"id":"1","name":"greet"}]`)
+	server.Script("GET", "/intents", http.StatusInternalServerError, "")
+
+	var names []string
+	var err error
+	for intent, iterErr := range server.Client().ListIntents(&wit.ListOptions{Limit: 1}).All(context.Background()) {
+		if iterErr != nil {
+			err = iterErr
+			break
+		}
+		names = append(names, intent.Name)
+	}
+	var apiErr *wit.APIError
+	if len(names) != 1 || !errors.As(err, &apiErr) || apiErr.StatusCode != 500 {
+		t.Errorf("names = %v, err = %v", names, err)
+	}
+}
+
+func TestListEntityValues(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddEntity(&wit.Entity{ID: "city", Values: []wit.EntityValue{{Value: "Paris"}, {Value: "Rome"}, {Value: "Oslo"}}})
+
+	values, err := server.Client().ListEntityValues("city", &wit.ListOptions{Limit: 2}).Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := len(server.Requested("GET", "/entities/city")); len(values) != 3 || values[2].Value != "Oslo" || calls != 1 {
+		t.Errorf("values = %+v after %d calls", values, calls)
+	}
+}
+
+func TestListApps(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	for i := 0; i < 3; i++ {
+		server.AddApp(&wit.App{ID: strconv.Itoa(i), Name: "app-" + strconv.Itoa(i), Lang: "en"})
+	}
+
+	apps, err := server.Client().ListApps(&wit.ListOptions{Limit: 2, Offset: 1}).Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 2 || apps[0].Name != "app-1" || apps[1].Lang != "en" {
+		t.Errorf("apps = %+v", apps)
+	}
+	if queries := listQueries(server, "/apps"); strings.Join(queries, "|") != "limit=2&offset=1&v=20151127|limit=2&offset=3&v=20151127" {
+		t.Errorf("queries = %v", queries)
+	}
+}
+
+func TestListUtterances(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.Script("GET", "/utterances", 200, `[
+		{"text":"wake me at 7","intent":{"id":"1","name":"alarm"},"entities":[{"id":"2","name":"wit$datetime","role":"datetime","start":11,"end":12,"body":"7","entities":[]}],"traits":[],"language":"en"},
+		{"text":"hello","intent":{"id":"3","name":"greeting"},"entities":[],"traits":[{"id":"4","name":"wit$sentiment","value":"positive"}]}]`)
+	server.Script("GET", "/utterances", 200, `[]`)
+
+	utterances, err := server.Client().ListUtterances(&wit.ListOptions{Limit: 2}).Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utterances) != 2 || utterances[0].Intent.Name != "alarm" || utterances[1].Traits[0].Value != "positive" {
+		t.Fatalf("utterances = %+v", utterances)
+	}
+	if entity := utterances[0].Entities[0]; entity.Name != "wit$datetime" || entity.Start != 11 || entity.Body != "7" {
+		t.Errorf("entity = %+v", entity)
+	}
+	if !strings.Contains(string(utterances[0].Raw), `"language":"en"`) {
+		t.Errorf("Unexpected raw %s", utterances[0].Raw)
+	}
+	if queries := listQueries(server, "/utterances"); strings.Join(queries, "|") != "limit=2&offset=0&v=20151127|limit=2&offset=2&v=20151127" {
+		t.Errorf("queries = %v", queries)
+	}
+}
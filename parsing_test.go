@@ -0,0 +1,174 @@
+// Copyright (c) 2014 Jason Goecke
+// parsing_test.go
+
+package wit
+
+import (
+	"testing"
+)
+
+func TestWitMessageParsing(t *testing.T) {
+	data := `
+	{
+	  "msg_id" : "2f41839e-2b54-4de2-aa59-fc016c3e58d1",
+	  "_text" : "how many people between Tuesday and Friday?",
+	  "outcomes" : [ {
+	    "_text" : "how many people between Tuesday and Friday?",
+	    "confidence" : 0.522,
+	    "intent" : "query_metrics",
+	    "entities" : {
+	      "datetime" : [ {
+	        "type" : "interval",
+	        "from" : {
+	          "value" : "2015-12-01T00:00:00.000-08:00",
+	          "grain" : "day"
+	        },
+	        "to" : {
+	          "value" : "2015-12-05T00:00:00.000-08:00",
+	          "grain" : "day"
+	        },
+	        "values" : [ {
+	          "type" : "interval",
+	          "from" : {
+	            "value" : "2015-12-01T00:00:00.000-08:00",
+	            "grain" : "day"
+	          },
+	          "to" : {
+	            "value" : "2015-12-05T00:00:00.000-08:00",
+	            "grain" : "day"
+	          }
+	        }, {
+	          "type" : "interval",
+	          "from" : {
+	            "value" : "2015-12-08T00:00:00.000-08:00",
+	            "grain" : "day"
+	          },
+	          "to" : {
+	            "value" : "2015-12-12T00:00:00.000-08:00",
+	            "grain" : "day"
+	          }
+	        }, {
+	          "type" : "interval",
+	          "from" : {
+	            "value" : "2015-12-15T00:00:00.000-08:00",
+	            "grain" : "day"
+	          },
+	          "to" : {
+	            "value" : "2015-12-19T00:00:00.000-08:00",
+	            "grain" : "day"
+	          }
+	        } ]
+	      } ]
+	    }
+	  } ]
+	}`
+
+	message, err := (&Client{}).parseMessage([]byte(data))
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	if message.MsgID != "2f41839e-2b54-4de2-aa59-fc016c3e58d1" {
+		t.Errorf("not equal %s != %s", "2f41839e-2b54-4de2-aa59-fc016c3e58d1", message.MsgID)
+	}
+	if message.Outcomes[0].Intent != "query_metrics" {
+		t.Errorf("not equal %s != %s", "query_metrics", message.Outcomes[0].Intent)
+	}
+	if message.Outcomes[0].Entities["datetime"][0].From.Grain != "day" {
+		t.Errorf("not equal %s != %s", "day", message.Outcomes[0].Entities["datetime"][0].From.Grain)
+	}
+}
+
+func TestWitEntitiesParsing(t *testing.T) {
+	data := `
+	[
+	   "wit$amount_of_money",
+	   "wit$contact",
+	   "wit$datetime",
+	   "wit$on_off",
+	   "wit$phrase_to_translate",
+	   "wit$temperature"
+	]`
+
+	entities, err := (&Client{}).parseEntities([]byte(data))
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	for cnt, value := range *entities {
+		switch cnt {
+		case 0:
+			if value != "wit$amount_of_money" {
+				t.Error("Entities JSON did not parse properly.")
+			}
+		case 3:
+			if value != "wit$on_off" {
+				t.Error("Entities JSON did not parse properly.")
+			}
+		case 5:
+			if value != "wit$temperature" {
+				t.Error("Entities JSON did not parse properly.")
+			}
+		}
+	}
+}
+
+func TestWitEntityParsing(t *testing.T) {
+	data := `
+	{
+	  "builtin": true,
+	  "doc": "Temperature in degrees Celcius or Fahrenheit",
+	  "id": "wit$temperature"
+	}`
+
+	entity, err := (&Client{}).parseEntity([]byte(data))
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	if entity.Builtin != true ||
+		entity.Doc != "Temperature in degrees Celcius or Fahrenheit" ||
+		entity.ID != "wit$temperature" {
+		t.Error("Message JSON did not parse properly.")
+	}
+}
+
+func TestWitIntentsParsing(t *testing.T) {
+	data := `
+	[ {
+	  "id" : "1234",
+	  "name" : "recover_password",
+	  "doc" : "Recover password (which is different from Reset password).",
+	  "metadata" : "password_23433253254"
+	}, {
+	  "id" : "52bab833-9a1e-4bff-b659-99ee95e6c1f9",
+	  "name" : "transfer",
+	  "doc" : "Transfer some amount of money between two accounts."
+	}, {
+	  "id" : "52bab833-3e23-4c67-9cfc-a0fed605bd77",
+	  "name" : "show_movie",
+	  "doc" : "Show a given movie."
+	} ]`
+
+	intents, err := (&Client{}).parseIntents([]byte(data))
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	for cnt, intent := range *intents {
+		switch cnt {
+		case 0:
+			if intent.ID != "1234" {
+				t.Error("Intents JSON did not parse properly.")
+			}
+		case 1:
+			if intent.Name != "transfer" {
+				t.Error("Intents JSON did not parse properly.")
+			}
+		case 2:
+			if intent.Doc != "Show a given movie." {
+				t.Error("Intents JSON did not parse properly.")
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2014 Jason Goecke
+// prometheus.go
+
+package wit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DurationBuckets are the upper bounds, in seconds, of the request latency histogram
+var DurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// SizeBuckets are the upper bounds, in bytes, of the response size histogram
+var SizeBuckets = []float64{256, 1024, 4096, 16384, 65536, 262144}
+
+// ConfidenceBuckets are the upper bounds of the intent confidence histogram
+var ConfidenceBuckets = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// PrometheusMetrics is a Metrics that keeps everything in memory and writes it
+// in the Prometheus text exposition format. It serves the metrics over HTTP,
+// so it can be mounted on any mux for scraping.
+//
+//		metrics := wit.NewPrometheusMetrics()
+//		client.Metrics = metrics
+//		http.Handle("/metrics", metrics)
+type PrometheusMetrics struct {
+	mu         sync.Mutex
+	requests   map[string]float64
+	errors     map[string]float64
+	durations  map[string]*histogram
+	sizes      map[string]*histogram
+	intents    map[string]float64
+	confidence map[string]*histogram
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewPrometheusMetrics creates an empty PrometheusMetrics
+//
+//		metrics := wit.NewPrometheusMetrics()
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		requests:   map[string]float64{},
+		errors:     map[string]float64{},
+		durations:  map[string]*histogram{},
+		sizes:      map[string]*histogram{},
+		intents:    map[string]float64{},
+		confidence: map[string]*histogram{},
+	}
+}
+
+// ObserveRequest implements Metrics
+func (metrics *PrometheusMetrics) ObserveRequest(observation *RequestObservation) {
+	status := "error"
+	if observation.StatusCode != 0 {
+		status = strconv.Itoa(observation.StatusCode)
+	}
+	series := labels("endpoint", observation.Endpoint, "verb", observation.Verb)
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.requests[labels("endpoint", observation.Endpoint, "verb", observation.Verb, "status", status)]++
+	if observation.ErrorClass != "" {
+		metrics.errors[labels("endpoint", observation.Endpoint, "verb", observation.Verb, "class", observation.ErrorClass)]++
+	}
+	observe(metrics.durations, series, DurationBuckets, observation.Duration.Seconds())
+	if observation.StatusCode != 0 {
+		observe(metrics.sizes, series, SizeBuckets, float64(observation.ResponseBytes))
+	}
+}
+
+// ObserveMessage implements Metrics, counting the intent of the best outcome
+// and observing its confidence
+func (metrics *PrometheusMetrics) ObserveMessage(message *Message) {
+	intent, confidence := "(none)", float64(0)
+	if len(message.Outcomes) > 0 && message.Outcomes[0].Intent != "" {
+		intent = message.Outcomes[0].Intent
+		confidence = float64(message.Outcomes[0].Confidence)
+	}
+	series := labels("intent", intent)
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.intents[series]++
+	observe(metrics.confidence, series, ConfidenceBuckets, confidence)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (metrics *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+//
+//		metrics.WriteTo(os.Stdout)
+func (metrics *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	metrics.mu.Lock()
+	writeCounter(&buf, "wit_requests_total", "Requests to the Wit API.", metrics.requests)
+	writeCounter(&buf, "wit_request_errors_total", "Failed requests to the Wit API by error class.", metrics.errors)
+	writeHistogram(&buf, "wit_request_duration_seconds", "Latency of requests to the Wit API.", metrics.durations)
+	writeHistogram(&buf, "wit_response_size_bytes", "Size of Wit API response bodies.", metrics.sizes)
+	writeCounter(&buf, "wit_message_intents_total", "Messages by the intent of their best outcome.", metrics.intents)
+	writeHistogram(&buf, "wit_message_confidence", "Confidence of the best outcome of messages.", metrics.confidence)
+	metrics.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+func observe(histograms map[string]*histogram, series string, buckets []float64, value float64) {
+	h, found := histograms[series]
+	if !found {
+		h = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		histograms[series] = h
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func writeCounter(buf *bytes.Buffer, name string, help string, counters map[string]float64) {
+	if len(counters) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	keys := make([]string, 0, len(counters))
+	for series := range counters {
+		keys = append(keys, series)
+	}
+	sort.Strings(keys)
+	for _, series := range keys {
+		fmt.Fprintf(buf, "%s{%s} %s\n", name, series, formatFloat(counters[series]))
+	}
+}
+
+func writeHistogram(buf *bytes.Buffer, name string, help string, histograms map[string]*histogram) {
+	if len(histograms) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	keys := make([]string, 0, len(histograms))
+	for series := range histograms {
+		keys = append(keys, series)
+	}
+	sort.Strings(keys)
+	for _, series := range keys {
+		h := histograms[series]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, series, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, series, h.count)
+		fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, series, formatFloat(h.sum))
+		fmt.Fprintf(buf, "%s_count{%s} %d\n", name, series, h.count)
+	}
+}
+
+// Formats label pairs as they appear between the braces of a series
+func labels(pairs ...string) string {
+	var parts []string
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for i := 0; i < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+replacer.Replace(pairs[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
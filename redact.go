@@ -0,0 +1,308 @@
+// Copyright (c) 2014 Jason Goecke
+// redact.go
+
+package wit
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Detector finds personal information in text
+type Detector interface {
+	Detect(text string) []PII
+}
+
+// PII represents personal information found by a Detector, at byte offsets
+// Start and End of the text
+type PII struct {
+	Kind  string
+	Start int
+	End   int
+}
+
+// RegexpDetector reports every match of Pattern as Kind, keeping only those
+// accepted by Validate when it is set
+type RegexpDetector struct {
+	Kind     string
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool
+}
+
+// Detect implements Detector
+func (detector *RegexpDetector) Detect(text string) []PII {
+	var found []PII
+	for _, match := range detector.Pattern.FindAllStringIndex(text, -1) {
+		if detector.Validate == nil || detector.Validate(text[match[0]:match[1]]) {
+			found = append(found, PII{Kind: detector.Kind, Start: match[0], End: match[1]})
+		}
+	}
+	return found
+}
+
+// EmailDetector finds email addresses
+//
+//		redactor := wit.NewRedactor(wit.EmailDetector())
+func EmailDetector() Detector {
+	return &RegexpDetector{
+		Kind:    "EMAIL",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+	}
+}
+
+// PhoneDetector finds phone numbers of 7 to 15 digits written either with a
+// leading + or in groups of at most 4 digits separated by spaces, dots or
+// dashes, with the area code optionally in parentheses. Dates such as
+// 2015-12-01 and amounts grouped in thousands such as 12 000 000 are not
+// phone numbers, and neither are runs of digits without a + like order 1234567.
+//
+//		redactor := wit.NewRedactor(wit.PhoneDetector())
+func PhoneDetector() Detector {
+	return &RegexpDetector{
+		Kind: "PHONE",
+		Pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .\-]?(?:\(\d{1,4}\)[ .\-]?)?|\(\d{1,4}\)[ .\-]?|\b)\d{1,4}(?:[ .\-]\d{1,4})*\b` +
+			`|\+\d{7,15}\b`),
+		Validate: phoneNumber,
+	}
+}
+
+// Reports whether a match of the PhoneDetector pattern is shaped like a
+// phone number rather than a date or an amount
+func phoneNumber(match string) bool {
+	digits := countDigits(match)
+	if digits < 7 || digits > 15 {
+		return false
+	}
+	if strings.ContainsAny(match, "+(") {
+		return true
+	}
+	groups := strings.FieldsFunc(match, func(c rune) bool { return c < '0' || c > '9' })
+	lengths := make([]int, len(groups))
+	for i, group := range groups {
+		lengths[i] = len(group)
+	}
+	if len(lengths) >= 3 && (slices.Equal(lengths[:3], []int{4, 2, 2}) || slices.Equal(lengths[:3], []int{2, 2, 4})) {
+		return false
+	}
+	thousands := lengths[0] <= 3
+	for _, length := range lengths[1:] {
+		thousands = thousands && length == 3
+	}
+	return !thousands
+}
+
+// CreditCardDetector finds card numbers of 13 to 19 digits, optionally
+// separated by spaces or dashes, that pass the Luhn check
+//
+//		redactor := wit.NewRedactor(wit.CreditCardDetector())
+func CreditCardDetector() Detector {
+	return &RegexpDetector{
+		Kind:     "CREDIT_CARD",
+		Pattern:  regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		Validate: luhn,
+	}
+}
+
+// AccountIDDetector finds account identifiers matching pattern, whose format
+// differs for every business
+//
+//		redactor := wit.NewRedactor(wit.AccountIDDetector(`\bACC-\d{8}\b`))
+func AccountIDDetector(pattern string) Detector {
+	return &RegexpDetector{Kind: "ACCOUNT_ID", Pattern: regexp.MustCompile(pattern)}
+}
+
+// DefaultDetectors returns the email, credit card and phone detectors
+func DefaultDetectors() []Detector {
+	return []Detector{EmailDetector(), CreditCardDetector(), PhoneDetector()}
+}
+
+func countDigits(text string) int {
+	digits := 0
+	for _, c := range text {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	return digits
+}
+
+// Reports whether the digits of text pass the Luhn checksum
+func luhn(text string) bool {
+	sum, digits := 0, 0
+	for i := len(text) - 1; i >= 0; i-- {
+		c := text[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+// Redactor masks personal information in messages before they are sent to
+// Wit. When set on a Client, Message sends the masked text and maps the
+// returned entities back onto the original text.
+//
+//		client.Redactor = wit.NewRedactor(append(wit.DefaultDetectors(), wit.AccountIDDetector(`\bACC-\d{8}\b`))...)
+type Redactor struct {
+	// Detectors are run in order; where findings overlap the earliest and
+	// then longest wins, with ties going to the earlier detector
+	Detectors []Detector
+	// Mask returns the text replacing a finding, "[KIND]" when nil
+	Mask func(pii PII) string
+}
+
+// Redaction represents a text with its personal information masked
+type Redaction struct {
+	Original     string
+	Masked       string
+	Replacements []Replacement
+}
+
+// Replacement represents one masked finding, at byte offsets of both the
+// original and masked texts
+type Replacement struct {
+	PII
+	MaskedStart int
+	MaskedEnd   int
+}
+
+// NewRedactor creates a Redactor, using DefaultDetectors when none are given
+//
+//		redactor := wit.NewRedactor()
+func NewRedactor(detectors ...Detector) *Redactor {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+	return &Redactor{Detectors: detectors}
+}
+
+// Redact masks the personal information found in text
+//
+//		redaction := redactor.Redact("mail me at jo@example.com")
+//		redaction.Masked // "mail me at [EMAIL]"
+func (redactor *Redactor) Redact(text string) *Redaction {
+	var found []PII
+	for _, detector := range redactor.Detectors {
+		found = append(found, detector.Detect(text)...)
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Start != found[j].Start {
+			return found[i].Start < found[j].Start
+		}
+		return found[i].End > found[j].End
+	})
+
+	redaction := &Redaction{Original: text}
+	masked := make([]byte, 0, len(text))
+	last := 0
+	for _, pii := range found {
+		if pii.Start < last || pii.Start >= pii.End {
+			continue
+		}
+		mask := "[" + pii.Kind + "]"
+		if redactor.Mask != nil {
+			mask = redactor.Mask(pii)
+		}
+		masked = append(masked, text[last:pii.Start]...)
+		replacement := Replacement{PII: pii, MaskedStart: len(masked)}
+		masked = append(masked, mask...)
+		replacement.MaskedEnd = len(masked)
+		redaction.Replacements = append(redaction.Replacements, replacement)
+		last = pii.End
+	}
+	redaction.Masked = string(append(masked, text[last:]...))
+	return redaction
+}
+
+// OriginalStart maps the start offset of a span of the masked text onto the
+// original text. A span starting inside a mask starts where the masked text did.
+func (redaction *Redaction) OriginalStart(offset int) int {
+	shift := 0
+	for _, replacement := range redaction.Replacements {
+		if offset < replacement.MaskedEnd {
+			if offset >= replacement.MaskedStart {
+				return replacement.Start
+			}
+			break
+		}
+		shift += (replacement.End - replacement.Start) - (replacement.MaskedEnd - replacement.MaskedStart)
+	}
+	return offset + shift
+}
+
+// OriginalEnd maps the end offset of a span of the masked text onto the
+// original text. A span ending inside a mask ends where the masked text did.
+func (redaction *Redaction) OriginalEnd(offset int) int {
+	shift := 0
+	for _, replacement := range redaction.Replacements {
+		if offset <= replacement.MaskedStart {
+			break
+		}
+		if offset <= replacement.MaskedEnd {
+			return replacement.End
+		}
+		shift += (replacement.End - replacement.Start) - (replacement.MaskedEnd - replacement.MaskedStart)
+	}
+	return offset + shift
+}
+
+// Restore returns a copy of a message for the masked text with its text put
+// back to the original and every entity span, body and matching string value
+// mapped onto the original text. Raw is rebuilt to match, with its keys sorted.
+//
+//		restored := redaction.Restore(message)
+func (redaction *Redaction) Restore(message *Message) *Message {
+	restored, _ := message.rewrite(redaction.Masked, redaction.Original, redaction.restoreEntity)
+	return restored
+}
+
+// Maps the span of an entity onto the original text, leaving spans outside
+// the masked text as they are
+func (redaction *Redaction) restoreEntity(entity *MessageEntity) bool {
+	maskedStart, maskedEnd := int(*entity.Start), int(*entity.End)
+	if maskedStart < 0 || maskedEnd > len(redaction.Masked) || maskedStart > maskedEnd {
+		return true
+	}
+	start, end := int64(redaction.OriginalStart(maskedStart)), int64(redaction.OriginalEnd(maskedEnd))
+	entity.Start, entity.End = &start, &end
+	maskedBody := redaction.Masked[maskedStart:maskedEnd]
+	body := redaction.Original[start:end]
+	if entity.Body != nil {
+		entity.Body = &body
+	}
+	if entity.Value != nil {
+		if value, isString := (*entity.Value).(string); isString && value == maskedBody {
+			restoredValue := interface{}(body)
+			entity.Value = &restoredValue
+		}
+	}
+	return true
+}
+
+// Masks the query before next sends it, restoring the answer onto the
+// original text. Requests without personal information pass straight through.
+func (redactor *Redactor) message(ctx context.Context, request *MessageRequest, next func(context.Context, *MessageRequest) (*Message, error)) (*Message, error) {
+	redaction := redactor.Redact(request.Query)
+	if len(redaction.Replacements) == 0 {
+		return next(ctx, request)
+	}
+	masked := *request
+	masked.Query = redaction.Masked
+	message, err := next(ctx, &masked)
+	if err != nil {
+		return nil, err
+	}
+	return redaction.Restore(message), nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2014 Jason Goecke
+// redact_test.go
+
+package wit_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func TestDetectors(t *testing.T) {
+	tests := []struct {
+		detector wit.Detector
+		text     string
+		expected []string
+	}{
+		{wit.EmailDetector(), "write to jo.smith+wit@mail.example.co.uk today", []string{"jo.smith+wit@mail.example.co.uk"}},
+		{wit.EmailDetector(), "no address @ here", nil},
+		{wit.PhoneDetector(), "call +1 (415) 555-0100 or 020 7946 0958", []string{"+1 (415) 555-0100", "020 7946 0958"}},
+		{wit.PhoneDetector(), "flight 1234 at 5", nil},
+		{wit.PhoneDetector(), "text +33612345678, 415.555.0100 or (415) 555-0100", []string{"+33612345678", "415.555.0100", "(415) 555-0100"}},
+		{wit.PhoneDetector(), "booked for 2015-12-01 or 01.12.2015 at 10:30", nil},
+		{wit.PhoneDetector(), "meet on 2015-12-01 09:30", nil},
+		{wit.PhoneDetector(), "where is order 1234567", nil},
+		{wit.PhoneDetector(), "send 12 000 000 dollars, or 1.250.000 euros", nil},
+		{wit.CreditCardDetector(), "card 4111 1111 1111 1111 and 4111-1111-1111-1112", []string{"4111 1111 1111 1111"}},
+		{wit.AccountIDDetector(`\bACC-\d{8}\b`), "account ACC-12345678, not ACC-123", []string{"ACC-12345678"}},
+	}
+	for _, test := range tests {
+		var actual []string
+		for _, pii := range test.detector.Detect(test.text) {
+			actual = append(actual, test.text[pii.Start:pii.End])
+		}
+		if strings.Join(actual, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("Detect(%q) = %q, want %q", test.text, actual, test.expected)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	redactor := wit.NewRedactor()
+	text := "card 4111 1111 1111 1111, mail jo@example.com"
+	redaction := redactor.Redact(text)
+	if redaction.Masked != "card [CREDIT_CARD], mail [EMAIL]" {
+		t.Fatalf("masked = %q", redaction.Masked)
+	}
+	if len(redaction.Replacements) != 2 || redaction.Replacements[0].Kind != "CREDIT_CARD" {
+		t.Errorf("replacements = %+v", redaction.Replacements)
+	}
+
+	redactor.Mask = func(pii wit.PII) string { return "xxx" }
+	if masked := redactor.Redact(text).Masked; masked != "card xxx, mail xxx" {
+		t.Errorf("custom mask = %q", masked)
+	}
+}
+
+func TestRedactionOffsets(t *testing.T) {
+	redaction := wit.NewRedactor().Redact("mail jo@example.com tomorrow")
+	// masked: "mail [EMAIL] tomorrow"
+	tests := []struct {
+		masked, start, end int
+	}{
+		{0, 0, 0},
+		{5, 5, 5},
+		{8, 5, 19},
+		{12, 19, 19},
+		{13, 20, 20},
+		{21, 28, 28},
+	}
+	for _, test := range tests {
+		if start := redaction.OriginalStart(test.masked); start != test.start {
+			t.Errorf("OriginalStart(%d) = %d, want %d", test.masked, start, test.start)
+		}
+		if end := redaction.OriginalEnd(test.masked); end != test.end {
+			t.Errorf("OriginalEnd(%d) = %d, want %d", test.masked, end, test.end)
+		}
+	}
+}
+
+func TestMessageRedaction(t *testing.T) {
+	original := "send the invoice to jo@example.com tomorrow"
+	masked := "send the invoice to [EMAIL] tomorrow"
+	start, end, later, last := int64(20), int64(27), int64(28), int64(36)
+	email, tomorrow := "[EMAIL]", "tomorrow"
+	value := interface{}("[EMAIL]")
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddMessage(masked, &wit.Message{Outcomes: []wit.Outcome{{
+		Text:   masked,
+		Intent: "send_invoice",
+		Entities: map[string][]wit.MessageEntity{
+			"email":    {{Start: &start, End: &end, Body: &email, Value: &value}},
+			"datetime": {{Start: &later, End: &last, Body: &tomorrow}},
+		},
+	}}})
+	client := server.Client()
+	client.Redactor = wit.NewRedactor()
+	client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(10, 0), "")
+
+	message, err := client.Message(&wit.MessageRequest{Query: original})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := server.AssertRequested(t, "GET", "/message").Query.Get("q"); sent != masked {
+		t.Errorf("sent %q", sent)
+	}
+	if message.Text != original || message.Outcomes[0].Text != original {
+		t.Errorf("text = %q", message.Text)
+	}
+	for name, expected := range map[string]string{"email": "jo@example.com", "datetime": "tomorrow"} {
+		entity := message.Outcomes[0].Entities[name][0]
+		if body := original[*entity.Start:*entity.End]; body != expected || *entity.Body != expected {
+			t.Errorf("%s span = %q, body %q", name, body, *entity.Body)
+		}
+	}
+	if value := *message.Outcomes[0].Entities["email"][0].Value; value != "jo@example.com" {
+		t.Errorf("email value = %v", value)
+	}
+	if strings.Contains(string(message.Raw), "[EMAIL]") || !strings.Contains(string(message.Raw), `"_text":"send the invoice to jo@example.com tomorrow"`) {
+		t.Errorf("Raw was not restored: %s", message.Raw)
+	}
+	if raw := string(message.Outcomes[0].Entities["email"][0].Raw); !strings.Contains(raw, `"body":"jo@example.com"`) || !strings.Contains(raw, `"start":20,`) || !strings.Contains(raw, `"end":34,`) {
+		t.Errorf("Entity raw was not restored: %s", raw)
+	}
+
+	cached, _ := client.Message(&wit.MessageRequest{Query: original})
+	if *cached.Outcomes[0].Entities["email"][0].Body != "jo@example.com" {
+		t.Error("cached message was not restored")
+	}
+}
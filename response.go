@@ -0,0 +1,53 @@
+// Copyright (c) 2014 Jason Goecke
+// response.go
+
+package wit
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the response header read into Response.RequestID
+const RequestIDHeader = "X-Request-Id"
+
+// Response represents the HTTP response to a request to the Wit API
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	RequestID  string
+	// Latency is the time from sending the request to reading the whole body
+	Latency time.Duration
+}
+
+type responseKey struct{}
+
+// WithResponse records into response the metadata of each request made with
+// ctx by any of the WithContext calls, including those that fail with an
+// *APIError. The response is left
+// untouched when no request is made, e.g. on a MessageCache hit, and when the
+// request fails before an answer is received. Requests sharing a MessageCache
+// call to Wit all get its response. Use a separate context for each request
+// when they run concurrently.
+//
+//		response := &wit.Response{}
+//		message, err := client.MessageWithContext(wit.WithResponse(ctx, response), request)
+//		log.Printf("request %s took %s", response.RequestID, response.Latency)
+func WithResponse(ctx context.Context, response *Response) context.Context {
+	return context.WithValue(ctx, responseKey{}, response)
+}
+
+// Fills in the Response asked for with WithResponse, if any
+func recordResponse(ctx context.Context, result *http.Response, latency time.Duration) {
+	response, ok := ctx.Value(responseKey{}).(*Response)
+	if !ok || response == nil {
+		return
+	}
+	*response = Response{
+		StatusCode: result.StatusCode,
+		Header:     result.Header,
+		RequestID:  result.Header.Get(RequestIDHeader),
+		Latency:    latency,
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2014 Jason Goecke
+// response_test.go
+
+package wit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func TestWithResponse(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.Intercept("GET", "/message", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(wit.RequestIDHeader, "req-"+r.URL.Query().Get("q"))
+		if r.URL.Query().Get("q") == "fail" {
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		}
+	})
+	client := server.Client()
+
+	response := &wit.Response{}
+	ctx := wit.WithResponse(context.Background(), response)
+	if _, err := client.MessageWithContext(ctx, &wit.MessageRequest{Query: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != 200 || response.RequestID != "req-hi" || response.Latency <= 0 {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	if response.Header.Get("Content-Type") == "" {
+		t.Errorf("Expected the response headers, got %v", response.Header)
+	}
+
+	_, err := client.MessageWithContext(ctx, &wit.MessageRequest{Query: "fail"})
+	var apiError *wit.APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("Expected an *APIError, got %v", err)
+	}
+	if response.StatusCode != http.StatusTooManyRequests || response.RequestID != "req-fail" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+}
+
+func TestWithResponseEveryCall(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addFavoriteCity(server)
+	server.SetSpeech(&wit.Message{Text: "hello"})
+	sent, err := client.Message(&wit.MessageRequest{Query: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := "A city I like"
+	// In order, since later calls need what earlier ones created
+	calls := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"AudioMessageWithContext", func(ctx context.Context) error {
+			_, err := client.AudioMessageWithContext(ctx, &wit.MessageRequest{FileContents: []byte("RIFF"), ContentType: "audio/wav"})
+			return err
+		}},
+		{"MessagesWithContext", func(ctx context.Context) error {
+			_, err := client.MessagesWithContext(ctx, sent.MsgID)
+			return err
+		}},
+		{"IntentsWithContext", func(ctx context.Context) error {
+			_, err := client.IntentsWithContext(ctx)
+			return err
+		}},
+		{"EntitiesWithContext", func(ctx context.Context) error {
+			_, err := client.EntitiesWithContext(ctx)
+			return err
+		}},
+		{"EntityWithContext", func(ctx context.Context) error {
+			_, err := client.EntityWithContext(ctx, "favorite_city")
+			return err
+		}},
+		{"CreateEntityWithContext", func(ctx context.Context) error {
+			_, err := client.CreateEntityWithContext(ctx, &wit.Entity{ID: "favorite_food"})
+			return err
+		}},
+		{"CreateEntityValueWithContext", func(ctx context.Context) error {
+			_, err := client.CreateEntityValueWithContext(ctx, "favorite_food", &wit.EntityValue{Value: "Paella"})
+			return err
+		}},
+		{"CreateEntityValueExpWithContext", func(ctx context.Context) error {
+			_, err := client.CreateEntityValueExpWithContext(ctx, "favorite_food", "Paella", "Arroz")
+			return err
+		}},
+		{"UpdateEntityWithContext", func(ctx context.Context) error {
+			_, err := client.UpdateEntityWithContext(ctx, &wit.Entity{ID: "favorite_city", Doc: doc})
+			return err
+		}},
+		{"PatchEntityWithContext", func(ctx context.Context) error {
+			_, err := client.PatchEntityWithContext(ctx, "favorite_city", &wit.EntityPatch{Doc: &doc})
+			return err
+		}},
+		{"DeleteEntityValueExpWithContext", func(ctx context.Context) error {
+			_, err := client.DeleteEntityValueExpWithContext(ctx, "favorite_food", "Paella", "Arroz")
+			return err
+		}},
+		{"DeleteEntityValueWithContext", func(ctx context.Context) error {
+			_, err := client.DeleteEntityValueWithContext(ctx, "favorite_food", "Paella")
+			return err
+		}},
+		{"DeleteEntityWithContext", func(ctx context.Context) error {
+			return client.DeleteEntityWithContext(ctx, "favorite_food")
+		}},
+	}
+	for _, call := range calls {
+		response := &wit.Response{}
+		if err := call.call(wit.WithResponse(context.Background(), response)); err != nil {
+			t.Errorf("%s: %v", call.name, err)
+			continue
+		}
+		if response.StatusCode != 200 || response.Latency <= 0 {
+			t.Errorf("%s: unexpected response %+v", call.name, response)
+		}
+	}
+
+	response := &wit.Response{}
+	if _, err := client.EntityWithContext(wit.WithResponse(context.Background(), response), "favorite_food"); err == nil {
+		t.Error("Expected a not found error")
+	}
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Unexpected response %+v", response)
+	}
+}
+
+func TestWithResponseCacheHit(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.Script("GET", "/message", 200, `{"msg_id":"1","_text":"hi","outcomes":[]}`)
+	client := server.Client()
+	client.MessageCache = wit.NewMessageCache(wit.NewLRUCache(10, 0), "")
+	if _, err := client.Message(&wit.MessageRequest{Query: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	response := &wit.Response{}
+	message, err := client.MessageWithContext(wit.WithResponse(context.Background(), response), &wit.MessageRequest{Query: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != 0 {
+		t.Errorf("Expected no response on a cache hit, got %+v", response)
+	}
+	if string(message.Raw) != `{"msg_id":"1","_text":"hi","outcomes":[]}` {
+		t.Errorf("Expected the cached message to keep its raw JSON, got %s", message.Raw)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2014 Jason Goecke
+// router.go
+
+package wit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// DefaultThreshold is the confidence below which a Router falls back
+const DefaultThreshold = 0.7
+
+// ErrNotRouted is returned by a Router that needs to fall back but has no
+// handler for it
+var ErrNotRouted = errors.New("wit: message not routed")
+
+// IntentHandler handles a message whose top outcome confidently matched the
+// intent it was registered for. Entities are available on the outcome.
+type IntentHandler func(ctx context.Context, outcome *Outcome, message *Message) error
+
+// FallbackHandler handles a message that no IntentHandler confidently matched
+type FallbackHandler func(ctx context.Context, fallback *Fallback) error
+
+// FallbackReason represents why a Router fell back
+type FallbackReason int
+
+const (
+	// NoOutcome means Wit returned no outcomes
+	NoOutcome FallbackReason = iota
+	// NoHandler means the top intent has no registered handler
+	NoHandler
+	// LowConfidence means the top outcome is below its intent's threshold
+	LowConfidence
+	// Ambiguous means other outcomes with different intents are within the
+	// router's AmbiguityMargin of the top outcome
+	Ambiguous
+)
+
+func (reason FallbackReason) String() string {
+	switch reason {
+	case NoOutcome:
+		return "no outcome"
+	case NoHandler:
+		return "no handler"
+	case LowConfidence:
+		return "low confidence"
+	case Ambiguous:
+		return "ambiguous"
+	}
+	return fmt.Sprintf("FallbackReason(%d)", int(reason))
+}
+
+// Fallback represents a message the Router could not confidently route.
+// Candidates holds the outcomes considered, highest confidence first; for
+// an Ambiguous fallback these are the best outcome of each competing intent,
+// to ask the user to choose from.
+type Fallback struct {
+	Reason     FallbackReason
+	Message    *Message
+	Candidates []Outcome
+}
+
+// Router sends each message to the handler registered for its top intent,
+// falling back on low confidence or ambiguity
+//
+//		router := wit.NewRouter(client)
+//		router.N = 3
+//		router.Handle("book_flight", func(ctx context.Context, outcome *wit.Outcome, message *wit.Message) error {
+//			...
+//		})
+//		router.HandleWithThreshold("cancel", 0.9, cancelHandler)
+//		router.Fallback = func(ctx context.Context, fallback *wit.Fallback) error {
+//			...
+//		}
+//		err := router.Route(ctx, &wit.MessageRequest{Query: text})
+type Router struct {
+	API MessageAPI
+	// Threshold is the confidence required for intents registered with
+	// Handle, DefaultThreshold when zero
+	Threshold float32
+	// AmbiguityMargin is how close in confidence another intent must be to
+	// the top outcome for the message to be ambiguous. Zero disables the check.
+	AmbiguityMargin float32
+	// N is the number of outcomes requested when the request does not set one
+	N int
+	// Fallback handles messages that could not be routed
+	Fallback FallbackHandler
+	// Clarify handles Ambiguous messages, Fallback is used when it is nil
+	Clarify FallbackHandler
+
+	routes map[string]route
+}
+
+type route struct {
+	threshold float32
+	handler   IntentHandler
+}
+
+// NewRouter creates a Router processing messages through api
+//
+//		router := wit.NewRouter(client)
+func NewRouter(api MessageAPI) *Router {
+	return &Router{API: api, AmbiguityMargin: 0.1, routes: map[string]route{}}
+}
+
+// Handle registers the handler for an intent using the router's Threshold
+//
+//		router.Handle("greeting", greetingHandler)
+func (router *Router) Handle(intent string, handler IntentHandler) {
+	router.HandleWithThreshold(intent, 0, handler)
+}
+
+// HandleWithThreshold registers the handler for an intent with its own
+// confidence threshold
+//
+//		router.HandleWithThreshold("transfer_money", 0.9, transferHandler)
+func (router *Router) HandleWithThreshold(intent string, threshold float32, handler IntentHandler) {
+	if router.routes == nil {
+		router.routes = map[string]route{}
+	}
+	router.routes[intent] = route{threshold, handler}
+}
+
+// Route processes the request with Message and dispatches the result
+//
+//		err := router.Route(ctx, &wit.MessageRequest{Query: "book a flight to Paris"})
+func (router *Router) Route(ctx context.Context, request *MessageRequest) error {
+	if request.N == 0 && router.N != 0 {
+		withN := *request
+		withN.N = router.N
+		request = &withN
+	}
+	var message *Message
+	var err error
+	if api, ok := router.API.(interface {
+		MessageWithContext(context.Context, *MessageRequest) (*Message, error)
+	}); ok {
+		message, err = api.MessageWithContext(ctx, request)
+	} else {
+		message, err = router.API.Message(request)
+	}
+	if err != nil {
+		return err
+	}
+	return router.Dispatch(ctx, message)
+}
+
+// Dispatch sends an already processed message to its handler or the fallback
+//
+//		err := router.Dispatch(ctx, message)
+func (router *Router) Dispatch(ctx context.Context, message *Message) error {
+	outcomes := append([]Outcome(nil), message.Outcomes...)
+	sort.SliceStable(outcomes, func(i, j int) bool {
+		return outcomes[i].Confidence > outcomes[j].Confidence
+	})
+	if len(outcomes) == 0 {
+		return router.fallback(ctx, &Fallback{NoOutcome, message, outcomes})
+	}
+
+	top := outcomes[0]
+	selected, found := router.routes[top.Intent]
+	if !found {
+		return router.fallback(ctx, &Fallback{NoHandler, message, outcomes})
+	}
+	if top.Confidence < router.threshold(selected) {
+		return router.fallback(ctx, &Fallback{LowConfidence, message, outcomes})
+	}
+	if router.AmbiguityMargin > 0 {
+		candidates := []Outcome{top}
+		seen := map[string]bool{top.Intent: true}
+		for _, outcome := range outcomes[1:] {
+			if !seen[outcome.Intent] && top.Confidence-outcome.Confidence < router.AmbiguityMargin {
+				candidates = append(candidates, outcome)
+				seen[outcome.Intent] = true
+			}
+		}
+		if len(candidates) > 1 {
+			return router.fallback(ctx, &Fallback{Ambiguous, message, candidates})
+		}
+	}
+	return selected.handler(ctx, &top, message)
+}
+
+func (router *Router) threshold(selected route) float32 {
+	if selected.threshold != 0 {
+		return selected.threshold
+	}
+	if router.Threshold != 0 {
+		return router.Threshold
+	}
+	return DefaultThreshold
+}
+
+func (router *Router) fallback(ctx context.Context, fallback *Fallback) error {
+	handler := router.Fallback
+	if fallback.Reason == Ambiguous && router.Clarify != nil {
+		handler = router.Clarify
+	}
+	if handler == nil {
+		return fmt.Errorf("%w: %s", ErrNotRouted, fallback.Reason)
+	}
+	return handler(ctx, fallback)
+}
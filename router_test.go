@@ -0,0 +1,151 @@
+// Copyright (c) 2014 Jason Goecke
+// router_test.go
+
+package wit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+// Returns canned messages by query text
+type stubMessageAPI map[string]*wit.Message
+
+func (stub stubMessageAPI) Message(request *wit.MessageRequest) (*wit.Message, error) {
+	message, found := stub[request.Query]
+	if !found {
+		return nil, errors.New("Not Found")
+	}
+	return message, nil
+}
+
+func (stub stubMessageAPI) Messages(id string) (*wit.Message, error) {
+	return nil, errors.New("Not Found")
+}
+
+func outcomes(pairs ...interface{}) *wit.Message {
+	message := &wit.Message{}
+	for i := 0; i < len(pairs); i += 2 {
+		message.Outcomes = append(message.Outcomes, wit.Outcome{Intent: pairs[i].(string), Confidence: float32(pairs[i+1].(float64))})
+	}
+	return message
+}
+
+func TestRouter(t *testing.T) {
+	stub := stubMessageAPI{
+		"book":      outcomes("book_flight", 0.92),
+		"unsure":    outcomes("book_flight", 0.55),
+		"transfer":  outcomes("transfer", 0.85),
+		"ambiguous": outcomes("cancel", 0.74, "book_flight", 0.81, "cancel", 0.80),
+		"unknown":   outcomes("weather", 0.99),
+		"nothing":   outcomes(),
+		"unsorted":  outcomes("weather", 0.2, "book_flight", 0.95),
+	}
+	router := wit.NewRouter(stub)
+	var handled string
+	var fallback *wit.Fallback
+	handler := func(ctx context.Context, outcome *wit.Outcome, message *wit.Message) error {
+		handled = outcome.Intent
+		return nil
+	}
+	router.Handle("book_flight", handler)
+	router.Handle("cancel", handler)
+	router.HandleWithThreshold("transfer", 0.9, handler)
+	router.Fallback = func(ctx context.Context, f *wit.Fallback) error {
+		fallback = f
+		return nil
+	}
+
+	tests := []struct {
+		query   string
+		handled string
+		reason  wit.FallbackReason
+	}{
+		{"book", "book_flight", -1},
+		{"unsure", "", wit.LowConfidence},
+		{"transfer", "", wit.LowConfidence},
+		{"ambiguous", "", wit.Ambiguous},
+		{"unknown", "", wit.NoHandler},
+		{"nothing", "", wit.NoOutcome},
+		{"unsorted", "book_flight", -1},
+	}
+	for _, test := range tests {
+		handled, fallback = "", nil
+		if err := router.Route(context.Background(), &wit.MessageRequest{Query: test.query}); err != nil {
+			t.Errorf("%s: %s", test.query, err)
+			continue
+		}
+		if handled != test.handled {
+			t.Errorf("%s: not equal %s != %s", test.query, test.handled, handled)
+		}
+		if test.reason >= 0 && (fallback == nil || fallback.Reason != test.reason) {
+			t.Errorf("%s: expected a %s fallback, got %+v", test.query, test.reason, fallback)
+		}
+		if test.reason < 0 && fallback != nil {
+			t.Errorf("%s: unexpected %s fallback", test.query, fallback.Reason)
+		}
+	}
+
+	router.Route(context.Background(), &wit.MessageRequest{Query: "ambiguous"})
+	if len(fallback.Candidates) != 2 || fallback.Candidates[0].Intent != "book_flight" || fallback.Candidates[1].Intent != "cancel" {
+		t.Errorf("Unexpected candidates %+v", fallback.Candidates)
+	}
+
+	var clarified bool
+	router.Clarify = func(ctx context.Context, f *wit.Fallback) error {
+		clarified = true
+		return nil
+	}
+	router.Route(context.Background(), &wit.MessageRequest{Query: "ambiguous"})
+	if !clarified {
+		t.Error("Ambiguous messages should go to Clarify when set")
+	}
+}
+
+func TestRouterErrors(t *testing.T) {
+	router := wit.NewRouter(stubMessageAPI{"nothing": outcomes()})
+	err := router.Route(context.Background(), &wit.MessageRequest{Query: "nothing"})
+	if !errors.Is(err, wit.ErrNotRouted) || err.Error() != "wit: message not routed: no outcome" {
+		t.Errorf("Expected ErrNotRouted, got %v", err)
+	}
+	if err = router.Route(context.Background(), &wit.MessageRequest{Query: "missing"}); err == nil || err.Error() != "Not Found" {
+		t.Errorf("Expected the wit.Message error, got %v", err)
+	}
+
+	failure := errors.New("handler failed")
+	router.API = stubMessageAPI{"book": outcomes("book_flight", 0.9)}
+	router.Handle("book_flight", func(ctx context.Context, outcome *wit.Outcome, message *wit.Message) error {
+		return failure
+	})
+	if err = router.Route(context.Background(), &wit.MessageRequest{Query: "book"}); err != failure {
+		t.Errorf("Expected the handler error, got %v", err)
+	}
+}
+
+func TestRouterRequestsN(t *testing.T) {
+	server := wittest.NewServer()
+	defer server.Close()
+	server.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting", Confidence: 0.9}}})
+
+	router := wit.NewRouter(server.Client())
+	router.N = 3
+	var intent string
+	router.Handle("greeting", func(ctx context.Context, outcome *wit.Outcome, message *wit.Message) error {
+		intent = outcome.Intent
+		return nil
+	})
+	request := &wit.MessageRequest{Query: "hello"}
+	if err := router.Route(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.AssertRequested(t, "GET", "/message").Query.Get("n"); n != "3" || intent != "greeting" {
+		t.Errorf("Unexpected n=%s intent=%s", n, intent)
+	}
+	if request.N != 0 {
+		t.Error("Route should not modify the request")
+	}
+}
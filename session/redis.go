@@ -0,0 +1,206 @@
+// Copyright (c) 2014 Jason Goecke
+// redis.go
+
+package session
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisStore keeps sessions in any server speaking the Redis protocol (RESP),
+// such as Redis, Valkey or KeyDB. Expiry is left to the server. One connection
+// is shared and re-dialed after a failure.
+type RedisStore struct {
+	Addr     string
+	Password string
+	DB       int
+	Prefix   string
+	Timeout  time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// RedisError represents an error reply from the server
+type RedisError string
+
+func (err RedisError) Error() string {
+	return "redis: " + string(err)
+}
+
+// NewRedisStore creates a RedisStore for the server at addr
+//
+//		store := session.NewRedisStore("localhost:6379")
+//		store.Password = os.Getenv("REDIS_PASSWORD")
+func NewRedisStore(addr string) *RedisStore {
+	return &RedisStore{Addr: addr, Prefix: "wit:session:", Timeout: 5 * time.Second}
+}
+
+// Get implements Store
+func (store *RedisStore) Get(ctx context.Context, id string) (*Data, error) {
+	reply, err := store.do(ctx, "GET", store.Prefix+id)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	contents, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+	data := &Data{}
+	if err = json.Unmarshal(contents, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Set implements Store
+func (store *RedisStore) Set(ctx context.Context, id string, data *Data, ttl time.Duration) error {
+	contents, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", store.Prefix + id, string(contents)}
+	if ttl > 0 {
+		// Rounded up, as Redis rejects PX 0 and the session must not expire early
+		milliseconds := (ttl + time.Millisecond - 1) / time.Millisecond
+		args = append(args, "PX", strconv.FormatInt(int64(milliseconds), 10))
+	}
+	_, err = store.do(ctx, args...)
+	return err
+}
+
+// Delete implements Store
+func (store *RedisStore) Delete(ctx context.Context, id string) error {
+	_, err := store.do(ctx, "DEL", store.Prefix+id)
+	return err
+}
+
+// Close closes the connection to the server
+func (store *RedisStore) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.closeConn()
+}
+
+// Sends a command and reads its reply, dropping the connection on any
+// failure other than an error reply so the next command re-dials
+func (store *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.conn == nil {
+		if err := store.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := store.command(ctx, args...)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		store.closeConn()
+	}
+	return reply, err
+}
+
+func (store *RedisStore) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: store.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", store.Addr)
+	if err != nil {
+		return err
+	}
+	store.conn = conn
+	store.reader = bufio.NewReader(conn)
+	if store.Password != "" {
+		if _, err = store.command(ctx, "AUTH", store.Password); err != nil {
+			store.closeConn()
+			return err
+		}
+	}
+	if store.DB != 0 {
+		if _, err = store.command(ctx, "SELECT", strconv.Itoa(store.DB)); err != nil {
+			store.closeConn()
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *RedisStore) closeConn() error {
+	if store.conn == nil {
+		return nil
+	}
+	err := store.conn.Close()
+	store.conn = nil
+	store.reader = nil
+	return err
+}
+
+func (store *RedisStore) command(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok && store.Timeout > 0 {
+		deadline = time.Now().Add(store.Timeout)
+	}
+	store.conn.SetDeadline(deadline)
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(store.conn, buf.String()); err != nil {
+		return nil, err
+	}
+	return readReply(store.reader)
+}
+
+// Reads one RESP reply: simple strings are returned as string, bulk strings
+// as []byte, integers as int64, arrays as []interface{} and nil as nil
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		contents := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, contents); err != nil {
+			return nil, err
+		}
+		return contents[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		replies := make([]interface{}, count)
+		for i := range replies {
+			if replies[i], err = readReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2014 Jason Goecke
+// redis_test.go
+
+package session
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A minimal RESP server supporting the commands RedisStore sends
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	now      time.Time
+	commands []string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeRedis{listener: listener, values: map[string]string{}, expires: map[string]time.Time{}, now: time.Now()}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (server *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+		fmt.Fprint(conn, server.execute(args))
+	}
+}
+
+func (server *fakeRedis) execute(args []string) string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.commands = append(server.commands, strings.Join(args, " "))
+	key := ""
+	if len(args) > 1 {
+		key = args[1]
+	}
+	if expires, found := server.expires[key]; found && !server.now.Before(expires) {
+		delete(server.values, key)
+		delete(server.expires, key)
+	}
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, found := server.values[key]
+		if !found {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		server.values[key] = args[2]
+		delete(server.expires, key)
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			server.expires[key] = server.now.Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, found := server.values[key]
+		delete(server.values, key)
+		if found {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func TestRedisStore(t *testing.T) {
+	server := newFakeRedis(t)
+	store := NewRedisStore(server.listener.Addr().String())
+	store.Password = "secret"
+	store.DB = 2
+	defer store.Close()
+	testStoreExpiry(t, store, func(d time.Duration) {
+		server.mu.Lock()
+		server.now = server.now.Add(d)
+		server.mu.Unlock()
+	})
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.commands[0] != "AUTH secret" || server.commands[1] != "SELECT 2" {
+		t.Errorf("handshake = %v", server.commands[:2])
+	}
+	if !strings.HasPrefix(server.commands[2], "SET wit:session:user/1 ") || !strings.HasSuffix(server.commands[2], " PX 60000") {
+		t.Errorf("SET = %q", server.commands[2])
+	}
+}
+
+func TestRedisStoreShortTTL(t *testing.T) {
+	server := newFakeRedis(t)
+	store := NewRedisStore(server.listener.Addr().String())
+	defer store.Close()
+	for _, ttl := range []time.Duration{time.Microsecond, 1500 * time.Microsecond} {
+		if err := store.Set(context.Background(), "user/1", &Data{}, ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.commands) != 2 || !strings.HasSuffix(server.commands[0], " PX 1") || !strings.HasSuffix(server.commands[1], " PX 2") {
+		t.Errorf("commands = %q", server.commands)
+	}
+}
+
+func TestRedisStoreErrors(t *testing.T) {
+	server := newFakeRedis(t)
+	store := NewRedisStore(server.listener.Addr().String())
+	store.Password = "wrong"
+	defer store.Close()
+	_, err := store.Get(context.Background(), "user-1")
+	if _, ok := err.(RedisError); !ok || err.Error() != "redis: WRONGPASS invalid password" {
+		t.Errorf("err = %v", err)
+	}
+
+	store = NewRedisStore("127.0.0.1:1")
+	if _, err = store.Get(context.Background(), "user-1"); err == nil {
+		t.Error("expected a dial error")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2014 Jason Goecke
+// session.go
+
+// Package session remembers each user's context and last results across
+// turns, filling in the Wit context of every message automatically.
+//
+//		manager := session.NewManager(client, session.NewMemoryStore(), 30*time.Minute)
+//		s, err := manager.Session(ctx, userID)
+//		s.SetTimezone("Europe/Paris")
+//		message, err := s.Message(ctx, &wit.MessageRequest{Query: "book a table tomorrow"})
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/dialog"
+)
+
+// Data represents everything remembered about a user between turns
+type Data struct {
+	Timezone     string                         `json:"timezone,omitempty"`
+	Locale       string                         `json:"locale,omitempty"`
+	Coords       *wit.Coords                    `json:"coords,omitempty"`
+	LastText     string                         `json:"last_text,omitempty"`
+	LastOutcomes []wit.Outcome                  `json:"last_outcomes,omitempty"`
+	LastEntities map[string][]wit.MessageEntity `json:"last_entities,omitempty"`
+	Dialog       *dialog.State                  `json:"dialog,omitempty"`
+	Values       map[string]string              `json:"values,omitempty"`
+	UpdatedAt    time.Time                      `json:"updated_at"`
+}
+
+// Manager creates sessions backed by a Store. Sessions expire TTL after
+// their last save; a zero TTL keeps them until deleted.
+type Manager struct {
+	// API processes the messages, usually a *wit.Client
+	API   wit.MessageAPI
+	Store Store
+	TTL   time.Duration
+	now   func() time.Time
+}
+
+// Session represents one user's conversation
+type Session struct {
+	ID      string
+	Data    *Data
+	manager *Manager
+}
+
+// NewManager creates a Manager
+//
+//		manager := session.NewManager(client, session.NewFileStore("/var/lib/bot/sessions"), time.Hour)
+func NewManager(api wit.MessageAPI, store Store, ttl time.Duration) *Manager {
+	return &Manager{API: api, Store: store, TTL: ttl, now: time.Now}
+}
+
+// Session loads the session for an id, starting a new one when it is missing
+// or has expired
+//
+//		s, err := manager.Session(ctx, userID)
+func (manager *Manager) Session(ctx context.Context, id string) (*Session, error) {
+	data, err := manager.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = &Data{}
+	}
+	return &Session{ID: id, Data: data, manager: manager}, nil
+}
+
+// SetTimezone sets the IANA timezone sent in the context of each message
+//
+//		s.SetTimezone("America/Los_Angeles")
+func (s *Session) SetTimezone(timezone string) {
+	s.Data.Timezone = timezone
+}
+
+// SetLocale sets the locale sent in the context of each message
+//
+//		s.SetLocale("en_US")
+func (s *Session) SetLocale(locale string) {
+	s.Data.Locale = locale
+}
+
+// Context builds the Wit context for the session: its timezone, locale and
+// coordinates, and the current time in its timezone as the reference time
+//
+//		context := s.Context()
+func (s *Session) Context() *wit.Context {
+	context := &wit.Context{Timezone: s.Data.Timezone, Locale: s.Data.Locale, Coords: s.Data.Coords}
+	if s.Data.Timezone != "" {
+		if location, err := time.LoadLocation(s.Data.Timezone); err == nil {
+			context.ReferenceTime = s.manager.now().In(location).Format("2006-01-02T15:04:05.000-07:00")
+		}
+	}
+	return context
+}
+
+// Message processes a text message with the session's context, unless the
+// request already has one, then remembers the outcomes and saves the session
+//
+//		message, err := s.Message(ctx, &wit.MessageRequest{Query: "and tomorrow?"})
+func (s *Session) Message(ctx context.Context, request *wit.MessageRequest) (*wit.Message, error) {
+	if request.Context == nil {
+		withContext := *request
+		withContext.Context = s.Context()
+		request = &withContext
+	}
+	message, err := s.manager.message(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	outcomes, err := copyOutcomes(message.Outcomes)
+	if err != nil {
+		return nil, err
+	}
+	s.Data.LastText = request.Query
+	s.Data.LastOutcomes = outcomes
+	s.Data.LastEntities = nil
+	if len(outcomes) > 0 {
+		s.Data.LastEntities = outcomes[0].Entities
+	}
+	return message, s.Save(ctx)
+}
+
+// Sends a message through the API, with ctx when the API supports it
+func (manager *Manager) message(ctx context.Context, request *wit.MessageRequest) (*wit.Message, error) {
+	if api, ok := manager.API.(interface {
+		MessageWithContext(context.Context, *wit.MessageRequest) (*wit.Message, error)
+	}); ok {
+		return api.MessageWithContext(ctx, request)
+	}
+	return manager.API.Message(request)
+}
+
+// Copies outcomes the way a Store would, so the session does not share them
+// with the message returned to the caller
+func copyOutcomes(outcomes []wit.Outcome) ([]wit.Outcome, error) {
+	if outcomes == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(outcomes)
+	if err != nil {
+		return nil, err
+	}
+	copied := []wit.Outcome{}
+	err = json.Unmarshal(data, &copied)
+	return copied, err
+}
+
+// Save stores the session, restarting its expiry
+//
+//		err := s.Save(ctx)
+func (s *Session) Save(ctx context.Context) error {
+	s.Data.UpdatedAt = s.manager.now()
+	return s.manager.Store.Set(ctx, s.ID, s.Data, s.manager.TTL)
+}
+
+// End deletes the session from the store
+//
+//		err := s.End(ctx)
+func (s *Session) End(ctx context.Context) error {
+	return s.manager.Store.Delete(ctx, s.ID)
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2014 Jason Goecke
+// session_test.go
+
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/witfake"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func TestSessionCarriesContext(t *testing.T) {
+	ctx := context.Background()
+	server := wittest.NewServer()
+	defer server.Close()
+	value := interface{}("Paris")
+	server.AddMessage("weather in Paris", &wit.Message{Outcomes: []wit.Outcome{{
+		Intent:     "weather",
+		Confidence: 0.9,
+		Entities:   map[string][]wit.MessageEntity{"location": {{Value: &value}}},
+	}}})
+
+	store := NewMemoryStore()
+	manager := NewManager(server.Client(), store, time.Hour)
+	manager.now = func() time.Time { return time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC) }
+	s, err := manager.Session(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetTimezone("Europe/Paris")
+	s.SetLocale("fr_FR")
+	if _, err = s.Message(ctx, &wit.MessageRequest{Query: "weather in Paris"}); err != nil {
+		t.Fatal(err)
+	}
+
+	context := &wit.Context{}
+	if err = json.Unmarshal([]byte(server.AssertRequested(t, "GET", "/message").Query.Get("context")), context); err != nil {
+		t.Fatal(err)
+	}
+	if context.Timezone != "Europe/Paris" || context.Locale != "fr_FR" {
+		t.Errorf("context = %+v", context)
+	}
+	if context.ReferenceTime != "2026-01-15T13:00:00.000+01:00" {
+		t.Errorf("reference time = %q", context.ReferenceTime)
+	}
+
+	s, err = manager.Session(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Data.Timezone != "Europe/Paris" || s.Data.LastText != "weather in Paris" {
+		t.Errorf("data = %+v", s.Data)
+	}
+	if len(s.Data.LastOutcomes) != 1 || s.Data.LastOutcomes[0].Intent != "weather" {
+		t.Errorf("last outcomes = %+v", s.Data.LastOutcomes)
+	}
+	if len(s.Data.LastEntities["location"]) != 1 {
+		t.Errorf("last entities = %+v", s.Data.LastEntities)
+	}
+}
+
+func TestSessionKeepsRequestContext(t *testing.T) {
+	ctx := context.Background()
+	server := wittest.NewServer()
+	defer server.Close()
+	manager := NewManager(server.Client(), NewMemoryStore(), 0)
+	s, _ := manager.Session(ctx, "user-1")
+	s.SetTimezone("Europe/Paris")
+	request := &wit.MessageRequest{Query: "hello", Context: &wit.Context{Timezone: "Asia/Tokyo"}}
+	if _, err := s.Message(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	server.AssertQuery(t, "context", `{"timezone":"Asia/Tokyo"}`)
+}
+
+func TestSessionEnd(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	manager := NewManager(&wit.Client{}, store, 0)
+	s, _ := manager.Session(ctx, "user-1")
+	s.SetLocale("en_US")
+	if err := s.Save(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.End(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s, _ = manager.Session(ctx, "user-1")
+	if s.Data.Locale != "" {
+		t.Errorf("ended session was kept: %+v", s.Data)
+	}
+}
+
+func TestSessionWithFake(t *testing.T) {
+	ctx := context.Background()
+	fake := witfake.New()
+	value := interface{}("Paris")
+	fake.AddMessage("weather in Paris", &wit.Message{Outcomes: []wit.Outcome{{
+		Intent:   "weather",
+		Entities: map[string][]wit.MessageEntity{"location": {{Value: &value}}},
+	}}})
+	manager := NewManager(fake, NewMemoryStore(), 0)
+	s, _ := manager.Session(ctx, "user-1")
+	message, err := s.Message(ctx, &wit.MessageRequest{Query: "weather in Paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.CallCount("Message") != 1 {
+		t.Errorf("calls = %+v", fake.Calls())
+	}
+
+	message.Outcomes[0].Intent = "changed"
+	message.Outcomes[0].Entities["location"] = nil
+	if s.Data.LastOutcomes[0].Intent != "weather" || len(s.Data.LastEntities["location"]) != 1 {
+		t.Errorf("session shares the returned message: %+v", s.Data)
+	}
+}
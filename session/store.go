@@ -0,0 +1,179 @@
+// Copyright (c) 2014 Jason Goecke
+// store.go
+
+package session
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store represents where sessions are kept between turns
+type Store interface {
+	// Get returns the session data for an id, or nil when it is missing or expired
+	Get(ctx context.Context, id string) (*Data, error)
+	// Set stores the session data, expiring it after ttl unless ttl is zero
+	Set(ctx context.Context, id string, data *Data, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// Represents stored session data along with its expiry
+type entry struct {
+	Data    json.RawMessage `json:"data"`
+	Expires time.Time       `json:"expires,omitempty"`
+}
+
+func newEntry(data *Data, ttl time.Duration, now time.Time) (*entry, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	stored := &entry{Data: encoded}
+	if ttl > 0 {
+		stored.Expires = now.Add(ttl)
+	}
+	return stored, nil
+}
+
+func (stored *entry) expired(now time.Time) bool {
+	return !stored.Expires.IsZero() && !now.Before(stored.Expires)
+}
+
+func (stored *entry) decode() (*Data, error) {
+	data := &Data{}
+	err := json.Unmarshal(stored.Data, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// MemoryStore is an in-process Store, safe for concurrent use. Expired
+// sessions are removed when they are next looked up.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+//
+//		store := session.NewMemoryStore()
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*entry{}, now: time.Now}
+}
+
+// Get implements Store
+func (store *MemoryStore) Get(ctx context.Context, id string) (*Data, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	stored, found := store.entries[id]
+	if !found {
+		return nil, nil
+	}
+	if stored.expired(store.now()) {
+		delete(store.entries, id)
+		return nil, nil
+	}
+	return stored.decode()
+}
+
+// Set implements Store
+func (store *MemoryStore) Set(ctx context.Context, id string, data *Data, ttl time.Duration) error {
+	stored, err := newEntry(data, ttl, store.now())
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.entries[id] = stored
+	return nil
+}
+
+// Delete implements Store
+func (store *MemoryStore) Delete(ctx context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.entries, id)
+	return nil
+}
+
+// FileStore keeps each session as a JSON file in a directory. Files are
+// written atomically, and expired ones are removed when next looked up.
+type FileStore struct {
+	Dir string
+	now func() time.Time
+}
+
+// NewFileStore creates a FileStore in dir, which is created on the first save
+//
+//		store := session.NewFileStore("/var/lib/bot/sessions")
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir, now: time.Now}
+}
+
+// Get implements Store
+func (store *FileStore) Get(ctx context.Context, id string) (*Data, error) {
+	contents, err := os.ReadFile(store.path(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	stored := &entry{}
+	if err = json.Unmarshal(contents, stored); err != nil {
+		return nil, err
+	}
+	if stored.expired(store.now()) {
+		return nil, store.Delete(ctx, id)
+	}
+	return stored.decode()
+}
+
+// Set implements Store
+func (store *FileStore) Set(ctx context.Context, id string, data *Data, ttl time.Duration) error {
+	stored, err := newEntry(data, ttl, store.now())
+	if err != nil {
+		return err
+	}
+	contents, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(store.Dir, 0700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(store.Dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(contents)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), store.path(id))
+}
+
+// Delete implements Store
+func (store *FileStore) Delete(ctx context.Context, id string) error {
+	err := os.Remove(store.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Hex encodes the id so any id makes a safe file name
+func (store *FileStore) path(id string) string {
+	return filepath.Join(store.Dir, hex.EncodeToString([]byte(id))+".json")
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2014 Jason Goecke
+// store_test.go
+
+package session
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func testStoreExpiry(t *testing.T, store Store, advance func(time.Duration)) {
+	ctx := context.Background()
+	if err := store.Set(ctx, "user/1", &Data{Locale: "en_US"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "user/2", &Data{Locale: "fr_FR"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := store.Get(ctx, "user/1")
+	if err != nil || data == nil || data.Locale != "en_US" {
+		t.Fatalf("Get = %+v, %v", data, err)
+	}
+
+	advance(time.Minute)
+	if data, err = store.Get(ctx, "user/1"); err != nil || data != nil {
+		t.Errorf("expired Get = %+v, %v", data, err)
+	}
+	if data, err = store.Get(ctx, "user/2"); err != nil || data == nil {
+		t.Errorf("Get without expiry = %+v, %v", data, err)
+	}
+
+	if err = store.Delete(ctx, "user/2"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = store.Get(ctx, "user/2"); err != nil || data != nil {
+		t.Errorf("deleted Get = %+v, %v", data, err)
+	}
+	if err = store.Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete missing = %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	testStoreExpiry(t, store, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestFileStore(t *testing.T) {
+	now := time.Now()
+	store := NewFileStore(t.TempDir())
+	store.now = func() time.Time { return now }
+	testStoreExpiry(t, store, func(d time.Duration) { now = now.Add(d) })
+
+	files, err := os.ReadDir(store.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("files left behind: %v", files)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2014 Jason Goecke
+// spans.go
+
+package wit
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// EntitySpan represents an entity located in a text. Start and End are byte
+// offsets, so Text is always text[Start:End].
+type EntitySpan struct {
+	Name   string
+	Text   string
+	Start  int
+	End    int
+	Entity MessageEntity
+}
+
+// Spans lists the entities of the message's best outcome located in its
+// text, treating Start and End as byte offsets
+//
+//		for _, span := range message.Spans() {
+//			fmt.Printf("%s: %q\n", span.Name, span.Text)
+//		}
+func (message *Message) Spans() []EntitySpan {
+	if len(message.Outcomes) == 0 {
+		return nil
+	}
+	return message.Outcomes[0].Spans(message.text())
+}
+
+// RuneSpans is Spans for offsets counted in runes rather than bytes. The
+// returned spans are converted to byte offsets.
+//
+//		spans := message.RuneSpans()
+func (message *Message) RuneSpans() []EntitySpan {
+	if len(message.Outcomes) == 0 {
+		return nil
+	}
+	return message.Outcomes[0].RuneSpans(message.text())
+}
+
+// Markup renders the message's text with its entities inline, e.g.
+// "flights to [Paris](location)"
+//
+//		fmt.Println(message.Markup())
+func (message *Message) Markup() string {
+	return Markup(message.text(), message.Spans(), nil)
+}
+
+// The text of a message, falling back to that of its best outcome
+func (message *Message) text() string {
+	if message.Text == "" && len(message.Outcomes) > 0 {
+		return message.Outcomes[0].Text
+	}
+	return message.Text
+}
+
+// Spans lists the entities of the outcome located in text, treating Start and
+// End as byte offsets. Entities without a span, or whose span is out of range
+// or splits a UTF-8 sequence, are left out. Spans are ordered by start, longest
+// first, then by name.
+//
+//		spans := outcome.Spans(message.Text)
+func (outcome Outcome) Spans(text string) []EntitySpan {
+	return outcome.spans(text, func(offset int) int {
+		if RuneOffset(text, offset) < 0 {
+			return -1
+		}
+		return offset
+	})
+}
+
+// RuneSpans is Spans for offsets counted in runes rather than bytes. The
+// returned spans are converted to byte offsets.
+//
+//		spans := outcome.RuneSpans(message.Text)
+func (outcome Outcome) RuneSpans(text string) []EntitySpan {
+	return outcome.spans(text, func(offset int) int {
+		return ByteOffset(text, offset)
+	})
+}
+
+func (outcome Outcome) spans(text string, toByte func(int) int) []EntitySpan {
+	var spans []EntitySpan
+	for name, entities := range outcome.Entities {
+		for _, entity := range entities {
+			if entity.Start == nil || entity.End == nil {
+				continue
+			}
+			start, end := toByte(int(*entity.Start)), toByte(int(*entity.End))
+			if start < 0 || end < 0 || start >= end {
+				continue
+			}
+			spans = append(spans, EntitySpan{Name: name, Text: text[start:end], Start: start, End: end, Entity: entity})
+		}
+	}
+	SortSpans(spans)
+	return spans
+}
+
+// SortSpans orders spans by start, longest first, then by name
+func SortSpans(spans []EntitySpan) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		a, b := spans[i], spans[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End > b.End
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Overlaps reports whether two spans share any of the text
+func (span EntitySpan) Overlaps(other EntitySpan) bool {
+	return span.Start < other.End && other.Start < span.End
+}
+
+// Runes returns the span as rune offsets into text
+//
+//		start, end := span.Runes(message.Text)
+func (span EntitySpan) Runes(text string) (int, int) {
+	return RuneOffset(text, span.Start), RuneOffset(text, span.End)
+}
+
+// Overlapping returns every pair of spans that overlap, in the order of spans
+//
+//		for _, pair := range wit.Overlapping(message.Spans()) {
+//			log.Printf("%s overlaps %s", pair[0].Name, pair[1].Name)
+//		}
+func Overlapping(spans []EntitySpan) [][2]EntitySpan {
+	var pairs [][2]EntitySpan
+	for i := range spans {
+		for j := i + 1; j < len(spans); j++ {
+			if spans[i].Overlaps(spans[j]) {
+				pairs = append(pairs, [2]EntitySpan{spans[i], spans[j]})
+			}
+		}
+	}
+	return pairs
+}
+
+// Markup renders text with each span inline as [text](name), skipping spans
+// that overlap one already rendered. Spans must be sorted, as Spans returns
+// them. When format is set it renders each span instead.
+//
+//		wit.Markup(text, spans, nil) // "flights to [Paris](location)"
+//		wit.Markup(text, spans, func(span wit.EntitySpan) string {
+//			return "<mark>" + span.Text + "</mark>"
+//		})
+func Markup(text string, spans []EntitySpan, format func(EntitySpan) string) string {
+	if format == nil {
+		format = func(span EntitySpan) string {
+			return "[" + span.Text + "](" + span.Name + ")"
+		}
+	}
+	var b strings.Builder
+	position := 0
+	for _, span := range spans {
+		if span.Start < position || span.End > len(text) {
+			continue
+		}
+		b.WriteString(text[position:span.Start])
+		b.WriteString(format(span))
+		position = span.End
+	}
+	b.WriteString(text[position:])
+	return b.String()
+}
+
+// RuneOffset converts a byte offset into text to a rune offset, returning -1
+// when it is out of range or splits a UTF-8 sequence
+//
+//		wit.RuneOffset("café au lait", 6) // 5
+func RuneOffset(text string, byteOffset int) int {
+	if byteOffset < 0 || byteOffset > len(text) || (byteOffset < len(text) && !utf8.RuneStart(text[byteOffset])) {
+		return -1
+	}
+	return utf8.RuneCountInString(text[:byteOffset])
+}
+
+// ByteOffset converts a rune offset into text to a byte offset, returning -1
+// when it is out of range
+//
+//		wit.ByteOffset("café au lait", 5) // 6
+func ByteOffset(text string, runeOffset int) int {
+	if runeOffset < 0 {
+		return -1
+	}
+	runes := 0
+	for i := range text {
+		if runes == runeOffset {
+			return i
+		}
+		runes++
+	}
+	if runes == runeOffset {
+		return len(text)
+	}
+	return -1
+}
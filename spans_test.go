@@ -0,0 +1,112 @@
+// Copyright (c) 2014 Jason Goecke
+// spans_test.go
+
+package wit
+
+import (
+	"testing"
+)
+
+func spanEntity(start int64, end int64, value interface{}) MessageEntity {
+	entity := MessageEntity{Start: &start, End: &end}
+	if value != nil {
+		entity.Value = &value
+	}
+	return entity
+}
+
+func TestSpans(t *testing.T) {
+	text := "vols pour Zürich à 9h"
+	message := &Message{Text: text, Outcomes: []Outcome{{Entities: map[string][]MessageEntity{
+		"datetime": {spanEntity(21, 23, nil)},
+		"location": {spanEntity(10, 17, "Zürich"), spanEntity(10, 13, nil)},
+		"city":     {spanEntity(10, 17, nil)},
+		"broken":   {spanEntity(12, 17, nil), spanEntity(5, 40, nil), spanEntity(9, 9, nil), {}},
+	}}}}
+
+	spans := message.Spans()
+	expected := []struct {
+		name, text string
+	}{
+		{"city", "Zürich"},
+		{"location", "Zürich"},
+		{"location", "Zü"},
+		{"datetime", "9h"},
+	}
+	if len(spans) != len(expected) {
+		t.Fatalf("spans = %+v", spans)
+	}
+	for i, span := range spans {
+		if span.Name != expected[i].name || span.Text != expected[i].text || text[span.Start:span.End] != span.Text {
+			t.Errorf("span %d = %+v, want %+v", i, span, expected[i])
+		}
+	}
+	if start, end := spans[0].Runes(text); start != 10 || end != 16 {
+		t.Errorf("Runes = %d, %d", start, end)
+	}
+	if pairs := Overlapping(spans); len(pairs) != 3 || pairs[0][0].Name != "city" || pairs[0][1].Name != "location" {
+		t.Errorf("Overlapping = %+v", pairs)
+	}
+	if spans[0].Overlaps(spans[3]) {
+		t.Error("disjoint spans overlap")
+	}
+}
+
+func TestRuneSpans(t *testing.T) {
+	text := "vols pour Zürich à 9h"
+	message := &Message{Outcomes: []Outcome{{Text: text, Entities: map[string][]MessageEntity{
+		"location": {spanEntity(10, 16, nil)},
+		"datetime": {spanEntity(19, 21, nil)},
+		"broken":   {spanEntity(19, 30, nil)},
+	}}}}
+	spans := message.RuneSpans()
+	if len(spans) != 2 || spans[0].Text != "Zürich" || spans[1].Text != "9h" {
+		t.Errorf("spans = %+v", spans)
+	}
+	if markup := Markup(text, spans, nil); markup != "vols pour [Zürich](location) à [9h](datetime)" {
+		t.Errorf("markup = %q", markup)
+	}
+}
+
+func TestMarkup(t *testing.T) {
+	message := &Message{Text: "flights to Paris tomorrow", Outcomes: []Outcome{{Entities: map[string][]MessageEntity{
+		"location": {spanEntity(11, 16, nil)},
+		"city":     {spanEntity(11, 14, nil)},
+		"datetime": {spanEntity(17, 25, nil)},
+	}}}}
+	if markup := message.Markup(); markup != "flights to [Paris](location) [tomorrow](datetime)" {
+		t.Errorf("markup = %q", markup)
+	}
+	markup := Markup(message.Text, message.Spans(), func(span EntitySpan) string { return "<" + span.Text + ">" })
+	if markup != "flights to <Paris> <tomorrow>" {
+		t.Errorf("custom markup = %q", markup)
+	}
+	if markup := (&Message{Text: "hi"}).Markup(); markup != "hi" {
+		t.Errorf("markup without outcomes = %q", markup)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	text := "café au lait"
+	tests := []struct {
+		bytes, runes int
+	}{
+		{0, 0}, {3, 3}, {5, 4}, {6, 5}, {13, 12},
+	}
+	for _, test := range tests {
+		if runes := RuneOffset(text, test.bytes); runes != test.runes {
+			t.Errorf("RuneOffset(%d) = %d, want %d", test.bytes, runes, test.runes)
+		}
+		if bytes := ByteOffset(text, test.runes); bytes != test.bytes {
+			t.Errorf("ByteOffset(%d) = %d, want %d", test.runes, bytes, test.bytes)
+		}
+	}
+	for _, invalid := range []int{-1, 4, 14} {
+		if runes := RuneOffset(text, invalid); runes != -1 {
+			t.Errorf("RuneOffset(%d) = %d, want -1", invalid, runes)
+		}
+	}
+	if bytes := ByteOffset(text, 13); bytes != -1 {
+		t.Errorf("ByteOffset(13) = %d, want -1", bytes)
+	}
+}
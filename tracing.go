@@ -0,0 +1,205 @@
+// Copyright (c) 2014 Jason Goecke
+// tracing.go
+
+package wit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer starts a span for every request a Client makes. It is shaped after
+// OpenTelemetry so an adapter over an OpenTelemetry trace.Tracer is a few
+// lines, while SpanRecorder covers tests without any dependency.
+//
+//		client.Tracer = otelAdapter{otel.Tracer("wit")}
+type Tracer interface {
+	// Start starts a span as a child of any span in ctx, returning a context
+	// carrying the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents one traced request
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// The attributes set on request spans
+const (
+	AttributeEndpoint   = "wit.endpoint"
+	AttributeVerb       = "http.request.method"
+	AttributeStatus     = "http.response.status_code"
+	AttributeMsgID      = "wit.msg_id"
+	AttributeIntent     = "wit.intent"
+	AttributeConfidence = "wit.confidence"
+	AttributeRetryCount = "wit.retry_count"
+	AttributeAudioBytes = "wit.audio_bytes"
+)
+
+type retryCountKey struct{}
+
+// WithRetryCount marks requests made with ctx as the nth retry, so their spans
+// carry the retry count. BatchMessage does this for its own retries.
+//
+//		message, err := client.MessageWithContext(wit.WithRetryCount(ctx, attempt), request)
+func WithRetryCount(ctx context.Context, retries int) context.Context {
+	return context.WithValue(ctx, retryCountKey{}, retries)
+}
+
+// Starts the span of a request, nil when the client has no Tracer
+func (client *Client) startSpan(ctx context.Context, httpParams *HTTPParams) (context.Context, Span) {
+	if client.Tracer == nil {
+		return ctx, nil
+	}
+	name := endpoint(httpParams.Resource)
+	ctx, span := client.Tracer.Start(ctx, "wit "+httpParams.Verb+" "+name)
+	span.SetAttribute(AttributeEndpoint, name)
+	span.SetAttribute(AttributeVerb, httpParams.Verb)
+	if retries, ok := ctx.Value(retryCountKey{}).(int); ok {
+		span.SetAttribute(AttributeRetryCount, retries)
+	}
+	if strings.HasPrefix(httpParams.ContentType, "audio/") {
+		span.SetAttribute(AttributeAudioBytes, len(httpParams.Data))
+	}
+	return ctx, span
+}
+
+// Ends the span of a request, recording its status and error, and for
+// message responses the msg_id and best intent
+func endSpan(span Span, statusCode int, message *Message, err error) {
+	if span == nil {
+		return
+	}
+	defer span.End()
+	if statusCode != 0 {
+		span.SetAttribute(AttributeStatus, statusCode)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if message == nil || message.MsgID == "" {
+		return
+	}
+	span.SetAttribute(AttributeMsgID, message.MsgID)
+	if len(message.Outcomes) > 0 {
+		span.SetAttribute(AttributeIntent, message.Outcomes[0].Intent)
+		span.SetAttribute(AttributeConfidence, message.Outcomes[0].Confidence)
+	}
+}
+
+// SpanRecorder is an in-memory Tracer keeping every ended span, for tests
+// and debugging
+//
+//		recorder := wit.NewSpanRecorder()
+//		client.Tracer = recorder
+//		client.Message(request)
+//		spans := recorder.Spans()
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan represents a span kept by a SpanRecorder
+type RecordedSpan struct {
+	Name       string
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Attributes map[string]interface{}
+	Errors     []error
+	Start      time.Time
+	End        time.Time
+
+	recorder *SpanRecorder
+}
+
+type spanKey struct{}
+
+// NewSpanRecorder creates an empty SpanRecorder
+//
+//		recorder := wit.NewSpanRecorder()
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Start implements Tracer. Spans started from a context carrying another
+// RecordedSpan share its trace and name it as their parent.
+func (recorder *SpanRecorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &recordingSpan{RecordedSpan: RecordedSpan{
+		Name:       name,
+		TraceID:    randomID(16),
+		SpanID:     randomID(8),
+		Attributes: map[string]interface{}{},
+		Start:      time.Now(),
+		recorder:   recorder,
+	}}
+	if parent, ok := ctx.Value(spanKey{}).(*recordingSpan); ok {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Spans returns copies of the ended spans in the order they ended
+func (recorder *SpanRecorder) Spans() []RecordedSpan {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	spans := make([]RecordedSpan, len(recorder.spans))
+	for i, span := range recorder.spans {
+		spans[i] = *span
+	}
+	return spans
+}
+
+// Reset forgets the ended spans
+func (recorder *SpanRecorder) Reset() {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.spans = nil
+}
+
+// The live span handed out by a SpanRecorder
+type recordingSpan struct {
+	mu sync.Mutex
+	RecordedSpan
+}
+
+func (span *recordingSpan) SetAttribute(key string, value interface{}) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.Attributes[key] = value
+}
+
+func (span *recordingSpan) RecordError(err error) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.Errors = append(span.Errors, err)
+}
+
+func (span *recordingSpan) End() {
+	span.mu.Lock()
+	span.RecordedSpan.End = time.Now()
+	ended := span.RecordedSpan
+	ended.Attributes = map[string]interface{}{}
+	for key, value := range span.Attributes {
+		ended.Attributes[key] = value
+	}
+	span.mu.Unlock()
+
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+	span.recorder.spans = append(span.recorder.spans, &ended)
+}
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
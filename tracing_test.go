@@ -0,0 +1,139 @@
+// Copyright (c) 2014 Jason Goecke
+// tracing_test.go
+
+package wit_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+func TestTracingMessage(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	recorder := wit.NewSpanRecorder()
+	client.Tracer = recorder
+	server.Script("GET", "/message", 200, `{"msg_id":"abc","_text":"hi","outcomes":[{"intent":"greeting","confidence":0.5}]}`)
+	ctx, parent := recorder.Start(context.Background(), "handle chat")
+	if _, err := client.MessageWithContext(ctx, &wit.MessageRequest{Query: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	span, root := spans[0], spans[1]
+	if span.Name != "wit GET /message" || span.ParentID != root.SpanID || span.TraceID != root.TraceID {
+		t.Errorf("span %+v is not a child of %+v", span, root)
+	}
+	expected := map[string]interface{}{
+		wit.AttributeEndpoint:   "/message",
+		wit.AttributeVerb:       "GET",
+		wit.AttributeStatus:     200,
+		wit.AttributeMsgID:      "abc",
+		wit.AttributeIntent:     "greeting",
+		wit.AttributeConfidence: float32(0.5),
+	}
+	for key, value := range expected {
+		if span.Attributes[key] != value {
+			t.Errorf("%s = %v, want %v", key, span.Attributes[key], value)
+		}
+	}
+	if len(span.Errors) != 0 || span.End.Before(span.Start) {
+		t.Errorf("span = %+v", span)
+	}
+}
+
+func TestTracingMessageParseError(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	recorder := wit.NewSpanRecorder()
+	client.Tracer = recorder
+	server.Script("GET", "/message", 200, `{"msg_id":`)
+	if _, err := client.Message(&wit.MessageRequest{Query: "hi"}); err == nil {
+		t.Fatal("Expected a parse error")
+	}
+
+	spans := recorder.Spans()
+	if len(spans) != 1 || len(spans[0].Errors) != 1 || spans[0].Attributes[wit.AttributeMsgID] != nil {
+		t.Errorf("Expected the parse error on the span: %+v", spans)
+	}
+	if _, ok := spans[0].Errors[0].(*wit.ParseError); !ok {
+		t.Errorf("errors = %v", spans[0].Errors)
+	}
+}
+
+func TestTracingEntities(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	recorder := wit.NewSpanRecorder()
+	client.Tracer = recorder
+	server.AddEntity(&wit.Entity{ID: "favorite_city", Values: []wit.EntityValue{{Value: "Paris"}}})
+	server.AddIntent("1", "greeting", "Say hello")
+	ctx, parent := recorder.Start(context.Background(), "sync entities")
+	if _, err := client.EntitiesWithContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateEntityValueExpWithContext(ctx, "favorite_city", "Paris", "Paname"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.IntentsWithContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := recorder.Spans()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	root := spans[3]
+	for _, span := range spans[:3] {
+		if span.ParentID != root.SpanID || span.TraceID != root.TraceID {
+			t.Errorf("span %+v is not a child of %+v", span, root)
+		}
+		if span.Attributes[wit.AttributeMsgID] != nil {
+			t.Errorf("Only message spans should carry a msg_id: %+v", span)
+		}
+	}
+	if spans[1].Name != "wit POST /entities/{id}/values/{value}/expressions" {
+		t.Errorf("Unexpected span name %s", spans[1].Name)
+	}
+}
+
+func TestTracingErrorsAndAudio(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	recorder := wit.NewSpanRecorder()
+	client.Tracer = recorder
+	server.Script("POST", "/speech", http.StatusServiceUnavailable, "")
+	client.AudioMessage(&wit.MessageRequest{FileContents: []byte("RIFF...."), ContentType: "audio/wav"})
+
+	spans := recorder.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "wit POST /speech" || span.Attributes[wit.AttributeAudioBytes] != 8 || span.Attributes[wit.AttributeStatus] != 503 {
+		t.Errorf("span = %+v", span)
+	}
+	if len(span.Errors) != 1 || span.Errors[0].Error() != "Service Unavailable" {
+		t.Errorf("errors = %v", span.Errors)
+	}
+}
+
+func TestTracingBatchRetries(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	recorder := wit.NewSpanRecorder()
+	client.Tracer = recorder
+	server.Script("GET", "/message", http.StatusTooManyRequests, "")
+	server.Script("GET", "/message", 200, `{"msg_id":"1"}`)
+	for range client.BatchMessage(context.Background(), feed("hi"), &wit.BatchOptions{MaxRetries: 1, RetryBackoff: 1}) {
+	}
+
+	spans := recorder.Spans()
+	if len(spans) != 2 || spans[0].Attributes[wit.AttributeRetryCount] != 0 || spans[1].Attributes[wit.AttributeRetryCount] != 1 {
+		t.Errorf("spans = %+v", spans)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2014 Jason Goecke
+// utterances.go
+
+package wit
+
+import (
+	"encoding/json"
+)
+
+// Utterances represents the utterances an app was trained with (https://wit.ai/docs/http#get__utterances_link)
+type Utterances []Utterance
+
+// Utterance represents a single training example within Utterances
+type Utterance struct {
+	Text     string            `json:"text"`
+	Intent   *UtteranceIntent  `json:"intent,omitempty"`
+	Entities []UtteranceEntity `json:"entities"`
+	Traits   []UtteranceTrait  `json:"traits"`
+	// Raw is the JSON the utterance was parsed from, including any fields
+	// this package does not know about
+	Raw json.RawMessage `json:"-"`
+}
+
+// UtteranceIntent represents the intent an utterance was labelled with
+type UtteranceIntent struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UtteranceEntity represents an entity labelled at byte offsets Start and End
+// of the utterance's text
+type UtteranceEntity struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Role     string            `json:"role"`
+	Start    int               `json:"start"`
+	End      int               `json:"end"`
+	Body     string            `json:"body"`
+	Entities []UtteranceEntity `json:"entities"`
+}
+
+// UtteranceTrait represents a trait an utterance was labelled with
+type UtteranceTrait struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Parses the JSON for Utterances
+func (client *Client) parseUtterances(data []byte) (*Utterances, error) {
+	utterances := &Utterances{}
+	err := client.decode(data, utterances)
+	if err != nil {
+		return nil, err
+	}
+	raw := []json.RawMessage{}
+	if json.Unmarshal(data, &raw) == nil && len(raw) == len(*utterances) {
+		for i := range raw {
+			(*utterances)[i].Raw = raw[i]
+		}
+	}
+	return utterances, nil
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2014 Jason Goecke
+// fake.go
+
+// Package witfake provides an in-memory implementation of wit.API for unit
+// testing code that depends on go-wit, without any HTTP involved.
+//
+//		fake := witfake.New()
+//		fake.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting"}}})
+//		handler := &Handler{Wit: fake}
+package witfake
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// ErrNotStubbed is returned by any method that has no canned result or stub function
+var ErrNotStubbed = errors.New("witfake: method not stubbed")
+
+// Call represents a method call received by a Fake
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Fake implements wit.API. Each method calls its Func field when set,
+// otherwise it returns a canned result or ErrNotStubbed.
+type Fake struct {
+	MessageFunc              func(request *wit.MessageRequest) (*wit.Message, error)
+	MessagesFunc             func(id string) (*wit.Message, error)
+	AudioMessageFunc         func(request *wit.MessageRequest) (*wit.Message, error)
+	EntitiesFunc             func() (*wit.Entities, error)
+	EntityFunc               func(id string) (*wit.Entity, error)
+	CreateEntityFunc         func(entity *wit.Entity) (*wit.Entity, error)
+	UpdateEntityFunc         func(entity *wit.Entity) (*wit.Entity, error)
+	PatchEntityFunc          func(id string, patch *wit.EntityPatch) (*wit.Entity, error)
+	DeleteEntityFunc         func(id string) error
+	CreateEntityValueFunc    func(id string, entityValue *wit.EntityValue) (*wit.Entity, error)
+	DeleteEntityValueFunc    func(id string, value string) (*wit.Deletion, error)
+	CreateEntityValueExpFunc func(id string, value string, exp string) (*wit.Entity, error)
+	DeleteEntityValueExpFunc func(id string, value string, exp string) (*wit.Deletion, error)
+	IntentsFunc              func() (*wit.Intents, error)
+
+	mu       sync.Mutex
+	messages map[string]*wit.Message
+	calls    []Call
+}
+
+var _ wit.API = (*Fake)(nil)
+
+// New creates a Fake with no canned results
+//
+//		fake := witfake.New()
+func New() *Fake {
+	return &Fake{messages: map[string]*wit.Message{}}
+}
+
+// AddMessage sets the message returned by Message for the given query text
+// and by Messages for the message's MsgID
+//
+//		fake.AddMessage("hello", &wit.Message{MsgID: "1", Outcomes: []wit.Outcome{{Intent: "greeting"}}})
+func (fake *Fake) AddMessage(query string, message *wit.Message) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.messages == nil {
+		fake.messages = map[string]*wit.Message{}
+	}
+	fake.messages[query] = message
+}
+
+// Calls returns every call received so far, oldest first
+//
+//		calls := fake.Calls()
+func (fake *Fake) Calls() []Call {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]Call(nil), fake.calls...)
+}
+
+// CallCount returns the number of calls received for the named method
+//
+//		count := fake.CallCount("Message")
+func (fake *Fake) CallCount(method string) int {
+	count := 0
+	for _, call := range fake.Calls() {
+		if call.Method == method {
+			count++
+		}
+	}
+	return count
+}
+
+func (fake *Fake) record(method string, args ...interface{}) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.calls = append(fake.calls, Call{method, args})
+}
+
+// Message implements wit.MessageAPI
+func (fake *Fake) Message(request *wit.MessageRequest) (*wit.Message, error) {
+	fake.record("Message", request)
+	if fake.MessageFunc != nil {
+		return fake.MessageFunc(request)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if message, found := fake.messages[request.Query]; found {
+		return message, nil
+	}
+	return nil, ErrNotStubbed
+}
+
+// Messages implements wit.MessageAPI
+func (fake *Fake) Messages(id string) (*wit.Message, error) {
+	fake.record("Messages", id)
+	if fake.MessagesFunc != nil {
+		return fake.MessagesFunc(id)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, message := range fake.messages {
+		if message.MsgID == id {
+			return message, nil
+		}
+	}
+	return nil, ErrNotStubbed
+}
+
+// AudioMessage implements wit.SpeechAPI
+func (fake *Fake) AudioMessage(request *wit.MessageRequest) (*wit.Message, error) {
+	fake.record("AudioMessage", request)
+	if fake.AudioMessageFunc != nil {
+		return fake.AudioMessageFunc(request)
+	}
+	return nil, ErrNotStubbed
+}
+
+// Entities implements wit.EntityAPI
+func (fake *Fake) Entities() (*wit.Entities, error) {
+	fake.record("Entities")
+	if fake.EntitiesFunc != nil {
+		return fake.EntitiesFunc()
+	}
+	return nil, ErrNotStubbed
+}
+
+// Entity implements wit.EntityAPI
+func (fake *Fake) Entity(id string) (*wit.Entity, error) {
+	fake.record("Entity", id)
+	if fake.EntityFunc != nil {
+		return fake.EntityFunc(id)
+	}
+	return nil, ErrNotStubbed
+}
+
+// CreateEntity implements wit.EntityAPI
+func (fake *Fake) CreateEntity(entity *wit.Entity) (*wit.Entity, error) {
+	fake.record("CreateEntity", entity)
+	if fake.CreateEntityFunc != nil {
+		return fake.CreateEntityFunc(entity)
+	}
+	return nil, ErrNotStubbed
+}
+
+// UpdateEntity implements wit.EntityAPI
+func (fake *Fake) UpdateEntity(entity *wit.Entity) (*wit.Entity, error) {
+	fake.record("UpdateEntity", entity)
+	if fake.UpdateEntityFunc != nil {
+		return fake.UpdateEntityFunc(entity)
+	}
+	return nil, ErrNotStubbed
+}
+
+// PatchEntity implements wit.EntityAPI
+func (fake *Fake) PatchEntity(id string, patch *wit.EntityPatch) (*wit.Entity, error) {
+	fake.record("PatchEntity", id, patch)
+	if fake.PatchEntityFunc != nil {
+		return fake.PatchEntityFunc(id, patch)
+	}
+	return nil, ErrNotStubbed
+}
+
+// DeleteEntity implements wit.EntityAPI
+func (fake *Fake) DeleteEntity(id string) error {
+	fake.record("DeleteEntity", id)
+	if fake.DeleteEntityFunc != nil {
+		return fake.DeleteEntityFunc(id)
+	}
+	return ErrNotStubbed
+}
+
+// CreateEntityValue implements wit.EntityAPI
+func (fake *Fake) CreateEntityValue(id string, entityValue *wit.EntityValue) (*wit.Entity, error) {
+	fake.record("CreateEntityValue", id, entityValue)
+	if fake.CreateEntityValueFunc != nil {
+		return fake.CreateEntityValueFunc(id, entityValue)
+	}
+	return nil, ErrNotStubbed
+}
+
+// DeleteEntityValue implements wit.EntityAPI
+func (fake *Fake) DeleteEntityValue(id string, value string) (*wit.Deletion, error) {
+	fake.record("DeleteEntityValue", id, value)
+	if fake.DeleteEntityValueFunc != nil {
+		return fake.DeleteEntityValueFunc(id, value)
+	}
+	return nil, ErrNotStubbed
+}
+
+// CreateEntityValueExp implements wit.EntityAPI
+func (fake *Fake) CreateEntityValueExp(id string, value string, exp string) (*wit.Entity, error) {
+	fake.record("CreateEntityValueExp", id, value, exp)
+	if fake.CreateEntityValueExpFunc != nil {
+		return fake.CreateEntityValueExpFunc(id, value, exp)
+	}
+	return nil, ErrNotStubbed
+}
+
+// DeleteEntityValueExp implements wit.EntityAPI
+func (fake *Fake) DeleteEntityValueExp(id string, value string, exp string) (*wit.Deletion, error) {
+	fake.record("DeleteEntityValueExp", id, value, exp)
+	if fake.DeleteEntityValueExpFunc != nil {
+		return fake.DeleteEntityValueExpFunc(id, value, exp)
+	}
+	return nil, ErrNotStubbed
+}
+
+// Intents implements wit.IntentAPI
+func (fake *Fake) Intents() (*wit.Intents, error) {
+	fake.record("Intents")
+	if fake.IntentsFunc != nil {
+		return fake.IntentsFunc()
+	}
+	return nil, ErrNotStubbed
+}
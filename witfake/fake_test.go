@@ -0,0 +1,70 @@
+// Copyright (c) 2014 Jason Goecke
+// fake_test.go
+
+package witfake
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// An example consumer that depends on the interface rather than *wit.Client
+func intentOf(api wit.MessageAPI, text string) (string, error) {
+	message, err := api.Message(&wit.MessageRequest{Query: text})
+	if err != nil {
+		return "", err
+	}
+	return message.Outcomes[0].Intent, nil
+}
+
+func TestFakeMessage(t *testing.T) {
+	fake := New()
+	fake.AddMessage("hello", &wit.Message{MsgID: "1", Outcomes: []wit.Outcome{{Intent: "greeting"}}})
+
+	intent, err := intentOf(fake, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intent != "greeting" {
+		t.Errorf("not equal %s != %s", "greeting", intent)
+	}
+	message, err := fake.Messages("1")
+	if err != nil || message.Outcomes[0].Intent != "greeting" {
+		t.Errorf("Messages did not return the canned message: %v", err)
+	}
+	if _, err = intentOf(fake, "goodbye"); err != ErrNotStubbed {
+		t.Errorf("Expected ErrNotStubbed, got %v", err)
+	}
+	if fake.CallCount("Message") != 2 {
+		t.Errorf("not equal %d != %d", 2, fake.CallCount("Message"))
+	}
+}
+
+func TestFakeFuncs(t *testing.T) {
+	failure := errors.New("boom")
+	fake := &Fake{
+		MessageFunc: func(request *wit.MessageRequest) (*wit.Message, error) {
+			return &wit.Message{Text: request.Query}, nil
+		},
+		DeleteEntityFunc: func(id string) error {
+			return failure
+		},
+	}
+
+	message, err := fake.Message(&wit.MessageRequest{Query: "anything"})
+	if err != nil || message.Text != "anything" {
+		t.Errorf("MessageFunc was not used: %v", err)
+	}
+	if err = fake.DeleteEntity("favorite_city"); err != failure {
+		t.Errorf("Expected the stubbed error, got %v", err)
+	}
+	if _, err = fake.Intents(); err != ErrNotStubbed {
+		t.Errorf("Expected ErrNotStubbed, got %v", err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 3 || calls[1].Method != "DeleteEntity" || calls[1].Args[0] != "favorite_city" {
+		t.Errorf("Calls not recorded properly: %+v", calls)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2014 Jason Goecke
+// limit.go
+
+package witserver
+
+import (
+	"sync"
+	"time"
+)
+
+// Token buckets per caller, refilled at rate tokens per second
+type limiters struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiters(rate float64, burst int) *limiters {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiters{rate: rate, burst: float64(burst), buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// Takes a token for the caller, returning how long to wait when none is left.
+// A nil limiters never limits.
+func (l *limiters) take(caller string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, found := l.buckets[caller]
+	if !found {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[caller] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
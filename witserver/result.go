@@ -0,0 +1,103 @@
+// Copyright (c) 2014 Jason Goecke
+// result.go
+
+package witserver
+
+import (
+	"sort"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// Result represents the normalized answer to a message. The best outcome is
+// flattened to the top level and every outcome is listed in Outcomes.
+type Result struct {
+	MsgID      string    `json:"msg_id"`
+	Text       string    `json:"text"`
+	Intent     string    `json:"intent"`
+	Confidence float32   `json:"confidence"`
+	Entities   []Entity  `json:"entities"`
+	Outcomes   []Outcome `json:"outcomes"`
+}
+
+// Outcome represents one normalized outcome of a message
+type Outcome struct {
+	Intent     string   `json:"intent"`
+	Confidence float32  `json:"confidence"`
+	Entities   []Entity `json:"entities"`
+}
+
+// Entity represents one normalized entity, listed in the order it appears
+// in the text
+type Entity struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value,omitempty"`
+	Body  string      `json:"body,omitempty"`
+	Start *int64      `json:"start,omitempty"`
+	End   *int64      `json:"end,omitempty"`
+	Unit  string      `json:"unit,omitempty"`
+	Grain string      `json:"grain,omitempty"`
+	Type  string      `json:"type,omitempty"`
+}
+
+// Normalize flattens a Wit message into a Result
+//
+//		result := witserver.Normalize(message)
+func Normalize(message *wit.Message) *Result {
+	result := &Result{MsgID: message.MsgID, Text: message.Text, Entities: []Entity{}, Outcomes: []Outcome{}}
+	for _, outcome := range message.Outcomes {
+		result.Outcomes = append(result.Outcomes, Outcome{
+			Intent:     outcome.Intent,
+			Confidence: outcome.Confidence,
+			Entities:   normalizeEntities(outcome.Entities),
+		})
+	}
+	if len(result.Outcomes) > 0 {
+		best := result.Outcomes[0]
+		result.Intent, result.Confidence, result.Entities = best.Intent, best.Confidence, best.Entities
+	}
+	return result
+}
+
+func normalizeEntities(entities map[string][]wit.MessageEntity) []Entity {
+	normalized := []Entity{}
+	for name, values := range entities {
+		for _, entity := range values {
+			normalized = append(normalized, Entity{
+				Name:  name,
+				Value: deref(entity.Value),
+				Body:  derefString(entity.Body),
+				Start: entity.Start,
+				End:   entity.End,
+				Unit:  derefString(entity.Unit),
+				Grain: derefString(entity.Grain),
+				Type:  derefString(entity.Type),
+			})
+		}
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		a, b := normalized[i], normalized[j]
+		if (a.Start == nil) != (b.Start == nil) {
+			return a.Start != nil
+		}
+		if a.Start != nil && *a.Start != *b.Start {
+			return *a.Start < *b.Start
+		}
+		return a.Name < b.Name
+	})
+	return normalized
+}
+
+func deref(value *interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2014 Jason Goecke
+// server.go
+
+// Package witserver exposes Wit message processing over HTTP, so services that
+// cannot or should not hold the Wit access token can still use it.
+//
+//		handler := witserver.New(client, &witserver.Options{
+//			Keys:              map[string]string{"<CALLER-KEY>": "billing"},
+//			RequestsPerSecond: 5,
+//		})
+//		http.ListenAndServe(":8080", handler)
+//
+// Callers send their key as "Authorization: Bearer <CALLER-KEY>" or in an
+// X-API-Key header, then use these endpoints:
+//
+//		POST /v1/message   {"text": "...", "context": {...}, "n": 2}, or GET /v1/message?q=...
+//		POST /v1/speech    the audio as the body, with its Content-Type
+//		GET  /healthz      200 while the process is up
+//		GET  /readyz       200 once Options.Ready succeeds
+//
+// Every answer is a normalized Result, and failures are {"error": "..."}.
+// Repeated messages are answered from the client's MessageCache when it has one.
+package witserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// DefaultMaxAudioBytes is the largest speech upload accepted by default
+const DefaultMaxAudioBytes = 10 << 20
+
+// Options configures a Handler. The zero value accepts anyone without limits.
+type Options struct {
+	// Keys maps each accepted API key to the name of its caller. When empty,
+	// no key is required.
+	Keys map[string]string
+	// RequestsPerSecond limits each caller, or all anonymous callers together,
+	// answering 429 beyond it. Zero disables rate limiting.
+	RequestsPerSecond float64
+	// Burst is how many requests a caller may make at once, at least 1
+	Burst int
+	// MaxAudioBytes limits speech uploads, DefaultMaxAudioBytes when zero
+	MaxAudioBytes int64
+	// Logger receives a line per request when set
+	Logger *slog.Logger
+	// Ready reports whether the handler can serve traffic, checked by /readyz
+	Ready func(ctx context.Context) error
+}
+
+// Handler is an http.Handler forwarding messages to Wit
+type Handler struct {
+	client   *wit.Client
+	opts     Options
+	mux      *http.ServeMux
+	limiters *limiters
+}
+
+// Represents the request body of /v1/message
+type messageBody struct {
+	Text     string              `json:"text"`
+	Context  *wit.Context        `json:"context,omitempty"`
+	N        int                 `json:"n,omitempty"`
+	Entities wit.DynamicEntities `json:"entities,omitempty"`
+}
+
+// Represents an error answer
+type errorBody struct {
+	Error  string `json:"error"`
+	Status int    `json:"wit_status,omitempty"`
+}
+
+type contextKey int
+
+const callerKey contextKey = iota
+
+// New creates a Handler sending messages through client
+//
+//		handler := witserver.New(client, nil)
+func New(client *wit.Client, options *Options) *Handler {
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.MaxAudioBytes <= 0 {
+		opts.MaxAudioBytes = DefaultMaxAudioBytes
+	}
+	handler := &Handler{client: client, opts: opts, mux: http.NewServeMux()}
+	if opts.RequestsPerSecond > 0 {
+		handler.limiters = newLimiters(opts.RequestsPerSecond, opts.Burst)
+	}
+	handler.mux.HandleFunc("GET /healthz", handler.health)
+	handler.mux.HandleFunc("GET /readyz", handler.ready)
+	handler.mux.Handle("GET /v1/message", handler.guard(handler.message))
+	handler.mux.Handle("POST /v1/message", handler.guard(handler.message))
+	handler.mux.Handle("POST /v1/speech", handler.guard(handler.speech))
+	return handler
+}
+
+// Caller returns the name of the caller authenticated for a request, or an
+// empty string when keys are not required
+//
+//		caller := witserver.Caller(r.Context())
+func Caller(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey).(string)
+	return caller
+}
+
+// ServeHTTP implements http.Handler, logging each request when a Logger is set
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler.opts.Logger == nil {
+		handler.mux.ServeHTTP(w, r)
+		return
+	}
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	handler.mux.ServeHTTP(recorder, r)
+	handler.opts.Logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("caller", recorder.caller),
+		slog.Int("status", recorder.status),
+		slog.Int64("bytes", recorder.bytes),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// Authenticates and rate limits the caller before calling next
+func (handler *Handler) guard(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := ""
+		if len(handler.opts.Keys) > 0 {
+			name, found := handler.opts.Keys[apiKey(r)]
+			if !found {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="wit"`)
+				writeError(w, http.StatusUnauthorized, errors.New("missing or unknown API key"))
+				return
+			}
+			caller = name
+		}
+		if recorder, ok := w.(*statusRecorder); ok {
+			recorder.caller = caller
+		}
+		if wait := handler.limiters.take(caller); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), callerKey, caller)))
+	})
+}
+
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func (handler *Handler) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (handler *Handler) ready(w http.ResponseWriter, r *http.Request) {
+	if handler.opts.Ready != nil {
+		if err := handler.opts.Ready(r.Context()); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (handler *Handler) message(w http.ResponseWriter, r *http.Request) {
+	body := &messageBody{}
+	if r.Method == http.MethodGet {
+		body.Text = r.URL.Query().Get("q")
+		if n := r.URL.Query().Get("n"); n != "" {
+			body.N, _ = strconv.Atoi(n)
+		}
+	} else {
+		decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+		if err := decoder.Decode(body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err))
+			return
+		}
+	}
+	if strings.TrimSpace(body.Text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("text is required"))
+		return
+	}
+	request := &wit.MessageRequest{Query: body.Text, Context: body.Context, N: body.N, Entities: body.Entities}
+	message, err := handler.client.MessageWithContext(r.Context(), request)
+	handler.respond(w, message, err)
+}
+
+func (handler *Handler) speech(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "audio/") {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("Content-Type must be an audio type"))
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, handler.opts.MaxAudioBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("audio body is required"))
+		return
+	}
+	request := &wit.MessageRequest{FileContents: data, ContentType: contentType}
+	query := r.URL.Query()
+	if n := query.Get("n"); n != "" {
+		request.N, _ = strconv.Atoi(n)
+	}
+	if raw := query.Get("context"); raw != "" {
+		request.Context = &wit.Context{}
+		if err = json.Unmarshal([]byte(raw), request.Context); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid context: %v", err))
+			return
+		}
+	}
+	message, err := handler.client.AudioMessageWithContext(r.Context(), request)
+	handler.respond(w, message, err)
+}
+
+// Writes a normalized message, or maps a Wit failure onto a status: Wit's
+// own rate limiting is passed on as 429 and anything else is a 502
+func (handler *Handler) respond(w http.ResponseWriter, message *wit.Message, err error) {
+	var apiErr *wit.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		status := http.StatusBadGateway
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			status = http.StatusTooManyRequests
+		}
+		writeJSON(w, status, &errorBody{Error: "wit: " + apiErr.Error(), Status: apiErr.StatusCode})
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, err)
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err)
+	case message == nil:
+		writeError(w, http.StatusBadGateway, errors.New("wit: empty response"))
+	default:
+		writeJSON(w, http.StatusOK, Normalize(message))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &errorBody{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Records what was written for request logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	caller string
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(data []byte) (int, error) {
+	n, err := recorder.ResponseWriter.Write(data)
+	recorder.bytes += int64(n)
+	return n, err
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2014 Jason Goecke
+// server_test.go
+
+package witserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsgoecke/go-wit"
+	"github.com/jsgoecke/go-wit/wittest"
+)
+
+// Teaches a fake Wit API the weather question
+func addWeather(server *wittest.Server) {
+	city := interface{}("Paris")
+	cityStart, cityEnd := int64(22), int64(27)
+	topicStart, topicEnd := int64(11), int64(18)
+	server.AddMessage("what's the weather in Paris", &wit.Message{Outcomes: []wit.Outcome{{
+		Intent:     "weather",
+		Confidence: 0.9,
+		Entities: map[string][]wit.MessageEntity{
+			"location": {{Value: &city, Start: &cityStart, End: &cityEnd}},
+			"topic":    {{Start: &topicStart, End: &topicEnd}},
+		},
+	}}})
+}
+
+func do(handler http.Handler, method string, target string, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestMessage(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addWeather(server)
+	handler := New(client, nil)
+	w := do(handler, "POST", "/v1/message", `{"text":"what's the weather in Paris","context":{"timezone":"Europe/Paris"},"n":2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	result := &Result{}
+	if err := json.Unmarshal(w.Body.Bytes(), result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Intent != "weather" || result.Confidence != 0.9 || len(result.Outcomes) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(result.Entities) != 2 || result.Entities[0].Name != "topic" || result.Entities[1].Value != "Paris" {
+		t.Errorf("entities = %+v", result.Entities)
+	}
+	server.AssertQuery(t, "context", `{"timezone":"Europe/Paris"}`)
+	server.AssertQuery(t, "n", "2")
+
+	w = do(handler, "GET", "/v1/message?q=what%27s+the+weather+in+Paris", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"intent":"weather"`) {
+		t.Errorf("GET = %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addWeather(server)
+	handler := New(client, nil)
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"text":`, http.StatusBadRequest},
+		{`{"text":"  "}`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if w := do(handler, "POST", "/v1/message", test.body); w.Code != test.status {
+			t.Errorf("%s: status = %d, want %d", test.body, w.Code, test.status)
+		}
+	}
+
+	server.Script("GET", "/message", http.StatusInternalServerError, `{"error":"boom"}`)
+	w := do(handler, "POST", "/v1/message", `{"text":"hello"}`)
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), `"wit_status":500`) {
+		t.Errorf("upstream failure = %d: %s", w.Code, w.Body)
+	}
+	server.Script("GET", "/message", http.StatusTooManyRequests, `{}`)
+	if w = do(handler, "POST", "/v1/message", `{"text":"hello"}`); w.Code != http.StatusTooManyRequests {
+		t.Errorf("upstream rate limit = %d", w.Code)
+	}
+}
+
+func TestSpeech(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addWeather(server)
+	server.SetSpeech(&wit.Message{Text: "hello world", Outcomes: []wit.Outcome{{Intent: "greeting", Confidence: 0.8}}})
+	handler := New(client, &Options{MaxAudioBytes: 8})
+	w := do(handler, "POST", "/v1/speech?n=1", "RIFF", "Content-Type", "audio/wav")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"text":"hello world"`) {
+		t.Fatalf("speech = %d: %s", w.Code, w.Body)
+	}
+	request := server.AssertRequested(t, "POST", "/speech")
+	if !bytes.Equal(request.Body, []byte("RIFF")) || request.Header.Get("Content-Type") != "audio/wav" {
+		t.Errorf("forwarded %q as %q", request.Body, request.Header.Get("Content-Type"))
+	}
+
+	if w = do(handler, "POST", "/v1/speech", "RIFF", "Content-Type", "text/plain"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("wrong type = %d", w.Code)
+	}
+	if w = do(handler, "POST", "/v1/speech", "RIFF-and-more", "Content-Type", "audio/wav"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too large = %d", w.Code)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addWeather(server)
+	var logs bytes.Buffer
+	handler := New(client, &Options{
+		Keys:   map[string]string{"key-1": "billing"},
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	if w := do(handler, "POST", "/v1/message", `{"text":"hello"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("without key = %d", w.Code)
+	}
+	if w := do(handler, "POST", "/v1/message", `{"text":"hello"}`, "Authorization", "Bearer nope"); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key = %d", w.Code)
+	}
+	if w := do(handler, "POST", "/v1/message", `{"text":"hello"}`, "Authorization", "Bearer key-1"); w.Code != http.StatusOK {
+		t.Errorf("bearer key = %d", w.Code)
+	}
+	if w := do(handler, "POST", "/v1/message", `{"text":"hello"}`, "X-API-Key", "key-1"); w.Code != http.StatusOK {
+		t.Errorf("X-API-Key = %d", w.Code)
+	}
+	if w := do(handler, "GET", "/healthz", ""); w.Code != http.StatusOK {
+		t.Errorf("healthz needs no key, got %d", w.Code)
+	}
+	if !strings.Contains(logs.String(), "caller=billing status=200") || !strings.Contains(logs.String(), "status=401") {
+		t.Errorf("logs = %s", logs.String())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server, client := wittest.NewTestServer(t)
+	addWeather(server)
+	now := time.Now()
+	handler := New(client, &Options{
+		Keys:              map[string]string{"key-1": "billing", "key-2": "search"},
+		RequestsPerSecond: 1,
+		Burst:             2,
+	})
+	handler.limiters.now = func() time.Time { return now }
+	send := func(key string) int {
+		return do(handler, "POST", "/v1/message", `{"text":"hello"}`, "X-API-Key", key).Code
+	}
+	if send("key-1") != 200 || send("key-1") != 200 {
+		t.Fatal("burst was limited")
+	}
+	w := do(handler, "POST", "/v1/message", `{"text":"hello"}`, "X-API-Key", "key-1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("over limit = %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if send("key-2") != 200 {
+		t.Error("callers share a limit")
+	}
+	now = now.Add(time.Second)
+	if send("key-1") != 200 {
+		t.Error("limit was not refilled")
+	}
+}
+
+func TestReady(t *testing.T) {
+	ready := errors.New("warming up")
+	handler := New(&wit.Client{}, &Options{Ready: func(ctx context.Context) error { return ready }})
+	if w := do(handler, "GET", "/readyz", ""); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "warming up") {
+		t.Errorf("not ready = %d: %s", w.Code, w.Body)
+	}
+	ready = nil
+	if w := do(handler, "GET", "/readyz", ""); w.Code != http.StatusOK {
+		t.Errorf("ready = %d", w.Code)
+	}
+}
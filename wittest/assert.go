@@ -0,0 +1,71 @@
+// Copyright (c) 2014 Jason Goecke
+// assert.go
+
+package wittest
+
+import (
+	"testing"
+)
+
+// Requested returns the recorded requests matching the method and path
+//
+//		requests := server.Requested("GET", "/message")
+func (server *Server) Requested(method string, path string) []Request {
+	var matches []Request
+	for _, request := range server.Requests() {
+		if request.Method == method && request.Path == path {
+			matches = append(matches, request)
+		}
+	}
+	return matches
+}
+
+// AssertRequested fails the test unless at least one request matched the
+// method and path, returning the most recent match
+//
+//		request := server.AssertRequested(t, "POST", "/entities")
+func (server *Server) AssertRequested(t testing.TB, method string, path string) Request {
+	t.Helper()
+	matches := server.Requested(method, path)
+	if len(matches) == 0 {
+		t.Errorf("expected a %s %s request, got none", method, path)
+		return Request{}
+	}
+	return matches[len(matches)-1]
+}
+
+// AssertNotRequested fails the test if any request matched the method and path
+//
+//		server.AssertNotRequested(t, "DELETE", "/entities/favorite_city")
+func (server *Server) AssertNotRequested(t testing.TB, method string, path string) {
+	t.Helper()
+	if matches := server.Requested(method, path); len(matches) > 0 {
+		t.Errorf("expected no %s %s request, got %d", method, path, len(matches))
+	}
+}
+
+// AssertRequestCount fails the test unless exactly count requests were received
+//
+//		server.AssertRequestCount(t, 2)
+func (server *Server) AssertRequestCount(t testing.TB, count int) {
+	t.Helper()
+	if requests := server.Requests(); len(requests) != count {
+		t.Errorf("expected %d requests, got %d", count, len(requests))
+	}
+}
+
+// AssertQuery fails the test unless the most recent request carried the query
+// parameter with the given value
+//
+//		server.AssertQuery(t, "q", "hello")
+func (server *Server) AssertQuery(t testing.TB, key string, value string) {
+	t.Helper()
+	request, ok := server.LastRequest()
+	if !ok {
+		t.Errorf("expected a request with %s=%s, got none", key, value)
+		return
+	}
+	if actual := request.Query.Get(key); actual != value {
+		t.Errorf("not equal %s=%s != %s=%s", key, value, key, actual)
+	}
+}
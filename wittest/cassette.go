@@ -0,0 +1,253 @@
+// Copyright (c) 2014 Jason Goecke
+// cassette.go
+
+package wittest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode sets whether a Cassette records real interactions or replays them
+type Mode int
+
+const (
+	// Replay serves responses from the cassette file and never touches the network
+	Replay Mode = iota
+	// Record sends requests through the underlying transport and keeps them for Save
+	Record
+)
+
+// Interaction represents a recorded request/response pair
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest represents the request half of an Interaction. The query
+// has its v= API version removed and the Authorization header is scrubbed.
+type RecordedRequest struct {
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Query      string      `json:"query,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"body_base64,omitempty"`
+}
+
+// RecordedResponse represents the response half of an Interaction
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"body_base64,omitempty"`
+}
+
+// Cassette is an http.RoundTripper that records Wit interactions to a JSON
+// file and replays them deterministically. In Replay mode a request that
+// matches no unused interaction fails with an error rather than reaching Wit.
+//
+//		mode := wittest.Replay
+//		if os.Getenv("WIT_RECORD") == "true" {
+//			mode = wittest.Record
+//		}
+//		cassette, err := wittest.NewCassette("testdata/hello.json", mode)
+//		client := wit.NewClient(os.Getenv("WIT_ACCESS_TOKEN"))
+//		client.HTTPClient = cassette.HTTPClient()
+//		defer cassette.Save()
+type Cassette struct {
+	Path string
+	Mode Mode
+	// Transport is used to reach Wit in Record mode, http.DefaultTransport when nil
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewCassette creates a cassette for the file at path, loading its
+// interactions when replaying
+//
+//		cassette, err := wittest.NewCassette("testdata/hello.json", wittest.Replay)
+func NewCassette(path string, mode Mode) (*Cassette, error) {
+	cassette := &Cassette{Path: path, Mode: mode}
+	if mode == Record {
+		return cassette, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &cassette.interactions)
+	if err != nil {
+		return nil, err
+	}
+	cassette.used = make([]bool, len(cassette.interactions))
+	return cassette, nil
+}
+
+// HTTPClient returns an http.Client using the cassette as its transport, for
+// use as wit.Client.HTTPClient
+//
+//		client.HTTPClient = cassette.HTTPClient()
+func (cassette *Cassette) HTTPClient() *http.Client {
+	return &http.Client{Transport: cassette}
+}
+
+// Interactions returns the interactions recorded or loaded so far
+//
+//		interactions := cassette.Interactions()
+func (cassette *Cassette) Interactions() []Interaction {
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+	return append([]Interaction(nil), cassette.interactions...)
+}
+
+// RoundTrip implements http.RoundTripper
+func (cassette *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, body, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if cassette.Mode == Record {
+		return cassette.record(req, recorded, body)
+	}
+	return cassette.replay(req, recorded)
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing
+// when replaying.
+//
+//		err := cassette.Save()
+func (cassette *Cassette) Save() error {
+	if cassette.Mode != Record {
+		return nil
+	}
+	cassette.mu.Lock()
+	data, err := json.MarshalIndent(cassette.interactions, "", "  ")
+	cassette.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cassette.Path, append(data, '\n'), 0644)
+}
+
+func (cassette *Cassette) record(req *http.Request, recorded RecordedRequest, body []byte) (*http.Response, error) {
+	transport := cassette.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	outbound := req.Clone(req.Context())
+	outbound.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := transport.RoundTrip(outbound)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	response := RecordedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone()}
+	response.Body, response.BodyBase64 = encodeBody(respBody)
+	cassette.mu.Lock()
+	cassette.interactions = append(cassette.interactions, Interaction{recorded, response})
+	cassette.used = append(cassette.used, true)
+	cassette.mu.Unlock()
+	return resp, nil
+}
+
+func (cassette *Cassette) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+	for i, interaction := range cassette.interactions {
+		if cassette.used[i] || !interaction.Request.matches(recorded) {
+			continue
+		}
+		cassette.used[i] = true
+		response := interaction.Response
+		body := decodeBody(response.Body, response.BodyBase64)
+		header := response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+			StatusCode:    response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("wittest: no unused interaction in %s matches %s %s?%s", cassette.Path, recorded.Method, recorded.Path, recorded.Query)
+}
+
+// Reports whether two recorded requests have the same method, path, query and body
+func (request RecordedRequest) matches(other RecordedRequest) bool {
+	return request.Method == other.Method &&
+		request.Path == other.Path &&
+		request.Query == other.Query &&
+		request.Body == other.Body &&
+		bytes.Equal(request.BodyBase64, other.BodyBase64)
+}
+
+// Builds the normalized, scrubbed form of a request, returning its body so it
+// can be sent on
+func recordRequest(req *http.Request) (RecordedRequest, []byte, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return RecordedRequest{}, nil, err
+		}
+	}
+	query, err := url.ParseQuery(req.URL.RawQuery)
+	if err != nil {
+		return RecordedRequest{}, nil, err
+	}
+	query.Del("v")
+
+	header := req.Header.Clone()
+	if header.Get("Authorization") != "" {
+		header.Set("Authorization", "Bearer [SCRUBBED]")
+	}
+	recorded := RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  query.Encode(),
+		Header: header,
+	}
+	recorded.Body, recorded.BodyBase64 = encodeBody(body)
+	return recorded, body, nil
+}
+
+// Keeps text bodies readable in the cassette file and base64 encodes binary ones
+func encodeBody(body []byte) (string, []byte) {
+	if utf8.Valid(body) {
+		return string(body), nil
+	}
+	return "", body
+}
+
+func decodeBody(text string, binary []byte) []byte {
+	if binary != nil {
+		return binary
+	}
+	return []byte(text)
+}
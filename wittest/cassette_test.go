@@ -0,0 +1,88 @@
+// Copyright (c) 2014 Jason Goecke
+// cassette_test.go
+
+package wittest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddMessage("hello", &wit.Message{MsgID: "abc-123", Outcomes: []wit.Outcome{{Intent: "greeting"}}})
+	server.SetSpeech(&wit.Message{MsgID: "def-456", Text: "hello world"})
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := NewCassette(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := wit.NewClient("secret-token")
+	client.APIBase = server.URL
+	client.HTTPClient = recorder.HTTPClient()
+	_, err = client.Message(&wit.MessageRequest{Query: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.AudioMessage(&wit.MessageRequest{FileContents: []byte{0x52, 0x49, 0xff, 0xfe}, ContentType: "audio/wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-token") {
+		t.Error("Bearer token was not scrubbed from the cassette")
+	}
+	if strings.Contains(string(data), wit.APIVersion) {
+		t.Error("API version was not removed from the cassette")
+	}
+
+	server.Close()
+	replayer, err := NewCassette(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.HTTPClient = replayer.HTTPClient()
+	message, err := client.Message(&wit.MessageRequest{Query: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.MsgID != "abc-123" || message.Outcomes[0].Intent != "greeting" {
+		t.Errorf("Recorded message not replayed properly: %+v", message)
+	}
+	message, err = client.AudioMessage(&wit.MessageRequest{FileContents: []byte{0x52, 0x49, 0xff, 0xfe}, ContentType: "audio/wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "hello world" {
+		t.Errorf("not equal %s != %s", "hello world", message.Text)
+	}
+
+	_, err = client.Message(&wit.MessageRequest{Query: "hello"})
+	if err == nil || !strings.Contains(err.Error(), "no unused interaction") {
+		t.Errorf("Expected an unmatched request error, got %v", err)
+	}
+	_, err = client.Message(&wit.MessageRequest{Query: "goodbye"})
+	if err == nil || !strings.Contains(err.Error(), "q=goodbye") {
+		t.Errorf("Expected an unmatched request error, got %v", err)
+	}
+}
+
+func TestCassetteMissingFile(t *testing.T) {
+	_, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), Replay)
+	if err == nil {
+		t.Error("Replaying a missing cassette should fail")
+	}
+}
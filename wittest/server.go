@@ -0,0 +1,571 @@
+// Copyright (c) 2014 Jason Goecke
+// server.go
+
+// Package wittest provides an in-process fake of the Wit API and a
+// record/replay Cassette transport for testing code that uses go-wit without
+// a network connection or an access token.
+//
+//		server := wittest.NewServer()
+//		defer server.Close()
+//		server.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting", Confidence: 0.9}}})
+//		client := server.Client()
+//		message, err := client.Message(&wit.MessageRequest{Query: "hello"})
+package wittest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+// Request represents a request received by the fake server
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Server is an httptest based fake of the Wit API that keeps entities,
+// intents, apps, utterances and messages in memory
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	entities   map[string]*wit.Entity
+	intents    wit.Intents
+	apps       wit.Apps
+	utterances wit.Utterances
+	messages   map[string]*wit.Message
+	queries    map[string]*wit.Message
+	speech     *wit.Message
+	scripts    map[string][]scriptedResponse
+	hooks      map[string]http.HandlerFunc
+	requests   []Request
+	nextID     int
+}
+
+type scriptedResponse struct {
+	status int
+	body   string
+}
+
+// NewServer starts a fake Wit API server with no entities, intents or messages
+//
+//		server := wittest.NewServer()
+//		defer server.Close()
+func NewServer() *Server {
+	server := &Server{
+		entities: map[string]*wit.Entity{},
+		messages: map[string]*wit.Message{},
+		queries:  map[string]*wit.Message{},
+		scripts:  map[string][]scriptedResponse{},
+		hooks:    map[string]http.HandlerFunc{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /message", server.message)
+	mux.HandleFunc("POST /speech", server.audioMessage)
+	mux.HandleFunc("GET /messages/{id}", server.storedMessage)
+	mux.HandleFunc("GET /intents", server.listIntents)
+	mux.HandleFunc("GET /apps", server.listApps)
+	mux.HandleFunc("GET /utterances", server.listUtterances)
+	mux.HandleFunc("GET /entities", server.listEntities)
+	mux.HandleFunc("POST /entities", server.createEntity)
+	mux.HandleFunc("GET /entities/{id}", server.getEntity)
+	mux.HandleFunc("PUT /entities/{id}", server.updateEntity)
+	mux.HandleFunc("DELETE /entities/{id}", server.deleteEntity)
+	mux.HandleFunc("POST /entities/{id}/values", server.createEntityValue)
+	mux.HandleFunc("DELETE /entities/{id}/values/{value}", server.deleteEntityValue)
+	mux.HandleFunc("POST /entities/{id}/values/{value}/expressions", server.createExpression)
+	mux.HandleFunc("DELETE /entities/{id}/values/{value}/expressions/{exp}", server.deleteExpression)
+	server.Server = httptest.NewServer(server.record(mux))
+	return server
+}
+
+// NewTestServer starts a fake Wit API server that is closed when the test
+// ends, returning it with a client pointed at it
+//
+//		server, client := wittest.NewTestServer(t)
+func NewTestServer(t testing.TB) (*Server, *wit.Client) {
+	t.Helper()
+	server := NewServer()
+	t.Cleanup(server.Close)
+	return server, server.Client()
+}
+
+// Client returns a wit.Client pointed at the fake server. Note that this sets
+// the package level wit.APIKey, just as wit.NewClient does.
+//
+//		client := server.Client()
+func (server *Server) Client() *wit.Client {
+	client := wit.NewClient("wittest-token")
+	client.APIBase = server.URL
+	return client
+}
+
+// AddMessage sets the message returned by /message for the given query text.
+// The returned message's text and msg_id are filled in when left empty.
+//
+//		server.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting"}}})
+func (server *Server) AddMessage(query string, message *wit.Message) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.queries[query] = message
+}
+
+// SetSpeech sets the message returned by /speech for any audio upload
+//
+//		server.SetSpeech(&wit.Message{Text: "hello world"})
+func (server *Server) SetSpeech(message *wit.Message) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.speech = message
+}
+
+// AddEntity stores a copy of an entity as if it had been created through the
+// API, so later requests do not modify the caller's entity
+//
+//		server.AddEntity(&wit.Entity{ID: "favorite_city", Doc: "A city that I like"})
+func (server *Server) AddEntity(entity *wit.Entity) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	copied := *entity
+	copied.Values = make([]wit.EntityValue, len(entity.Values))
+	for i, value := range entity.Values {
+		copied.Values[i] = wit.EntityValue{Value: value.Value, Expressions: append([]string(nil), value.Expressions...)}
+	}
+	server.entities[entity.ID] = &copied
+}
+
+// AddIntent stores an intent returned by /intents
+//
+//		server.AddIntent("1234", "recover_password", "Recover password")
+func (server *Server) AddIntent(id string, name string, doc string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.intents = append(server.intents, wit.Intents{{ID: id, Name: name, Doc: doc}}...)
+}
+
+// AddApp stores an app returned by /apps
+//
+//		server.AddApp(&wit.App{ID: "1234", Name: "weather", Lang: "en"})
+func (server *Server) AddApp(app *wit.App) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.apps = append(server.apps, *app)
+}
+
+// AddUtterance stores an utterance returned by /utterances
+//
+//		server.AddUtterance(&wit.Utterance{Text: "hello", Intent: &wit.UtteranceIntent{Name: "greeting"}})
+func (server *Server) AddUtterance(utterance *wit.Utterance) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.utterances = append(server.utterances, *utterance)
+}
+
+// Script queues a canned response for the next request matching the method
+// and path, taking precedence over the in-memory behaviour. Responses queued
+// for the same route are returned in order.
+//
+//		server.Script("GET", "/entities/favorite_city", 500, "")
+func (server *Server) Script(method string, path string, status int, body string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	key := method + " " + path
+	server.scripts[key] = append(server.scripts[key], scriptedResponse{status, body})
+}
+
+// Intercept calls fn for every request matching the method and path, before
+// any scripted response or the in-memory behaviour, which only run when fn
+// writes no response. Use it to delay, fail or add headers to some requests;
+// fn may also hijack the connection to simulate a network failure. Setting fn
+// to nil removes the hook.
+//
+//		server.Intercept("GET", "/message", func(w http.ResponseWriter, r *http.Request) {
+//			if r.URL.Query().Get("q") == "flaky" {
+//				w.WriteHeader(http.StatusServiceUnavailable)
+//			}
+//		})
+func (server *Server) Intercept(method string, path string, fn http.HandlerFunc) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	key := method + " " + path
+	if fn == nil {
+		delete(server.hooks, key)
+		return
+	}
+	server.hooks[key] = fn
+}
+
+// Requests returns every request received so far, oldest first
+//
+//		requests := server.Requests()
+func (server *Server) Requests() []Request {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]Request(nil), server.requests...)
+}
+
+// LastRequest returns the most recent request received, if any
+//
+//		request, ok := server.LastRequest()
+func (server *Server) LastRequest() (Request, bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.requests) == 0 {
+		return Request{}, false
+	}
+	return server.requests[len(server.requests)-1], true
+}
+
+// Reset clears all state, scripts and recorded requests
+//
+//		server.Reset()
+func (server *Server) Reset() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.entities = map[string]*wit.Entity{}
+	server.intents = nil
+	server.apps = nil
+	server.utterances = nil
+	server.messages = map[string]*wit.Message{}
+	server.queries = map[string]*wit.Message{}
+	server.speech = nil
+	server.scripts = map[string][]scriptedResponse{}
+	server.hooks = map[string]http.HandlerFunc{}
+	server.requests = nil
+}
+
+// Records each request and serves any scripted response before routing
+func (server *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		server.mu.Lock()
+		server.requests = append(server.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		key := r.Method + " " + r.URL.Path
+		hook := server.hooks[key]
+		server.mu.Unlock()
+
+		if hook != nil {
+			recorder := &hookWriter{ResponseWriter: w}
+			hook(recorder, r)
+			if recorder.written {
+				return
+			}
+		}
+
+		server.mu.Lock()
+		scripts := server.scripts[key]
+		var script *scriptedResponse
+		if len(scripts) > 0 {
+			script = &scripts[0]
+			server.scripts[key] = scripts[1:]
+		}
+		server.mu.Unlock()
+
+		if script != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(script.status)
+			io.WriteString(w, script.body)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Notes whether an Intercept hook wrote a response
+type hookWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *hookWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *hookWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *hookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.written = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (server *Server) message(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	server.mu.Lock()
+	message := server.respond(server.queries[query], query, r.URL.Query().Get("msg_id"))
+	server.mu.Unlock()
+	writeJSON(w, http.StatusOK, message)
+}
+
+func (server *Server) audioMessage(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	message := server.respond(server.speech, "", r.URL.Query().Get("msg_id"))
+	server.mu.Unlock()
+	writeJSON(w, http.StatusOK, message)
+}
+
+func (server *Server) storedMessage(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	message, found := server.messages[r.PathValue("id")]
+	server.mu.Unlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, message)
+}
+
+// Copies the canned message, fills in its text and id and stores it so it can
+// be fetched again from /messages. Must be called with the lock held.
+func (server *Server) respond(canned *wit.Message, text string, msgID string) *wit.Message {
+	message := &wit.Message{Text: text, Outcomes: []wit.Outcome{}}
+	if canned != nil {
+		copied := *canned
+		message = &copied
+		if message.Text == "" {
+			message.Text = text
+		}
+	}
+	if msgID != "" {
+		message.MsgID = msgID
+	}
+	if message.MsgID == "" {
+		server.nextID++
+		message.MsgID = "wittest-" + strconv.Itoa(server.nextID)
+	}
+	server.messages[message.MsgID] = message
+	return message
+}
+
+func (server *Server) listIntents(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	intents := append(wit.Intents{}, server.intents...)
+	server.mu.Unlock()
+	start, end := page(r, len(intents))
+	writeJSON(w, http.StatusOK, intents[start:end])
+}
+
+func (server *Server) listApps(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	apps := append(wit.Apps{}, server.apps...)
+	server.mu.Unlock()
+	start, end := page(r, len(apps))
+	writeJSON(w, http.StatusOK, apps[start:end])
+}
+
+func (server *Server) listUtterances(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	utterances := append(wit.Utterances{}, server.utterances...)
+	server.mu.Unlock()
+	start, end := page(r, len(utterances))
+	writeJSON(w, http.StatusOK, utterances[start:end])
+}
+
+func (server *Server) listEntities(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	entities := wit.Entities{}
+	for id := range server.entities {
+		entities = append(entities, id)
+	}
+	server.mu.Unlock()
+	sort.Strings(entities)
+	start, end := page(r, len(entities))
+	writeJSON(w, http.StatusOK, entities[start:end])
+}
+
+// Returns the bounds of the page selected by the limit and offset query
+// parameters, or of everything when they are absent
+func page(r *http.Request, total int) (int, int) {
+	start, end := 0, total
+	if offset, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && offset > 0 {
+		start = min(offset, total)
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		end = min(start+limit, total)
+	}
+	return start, end
+}
+
+func (server *Server) createEntity(w http.ResponseWriter, r *http.Request) {
+	entity := &wit.Entity{}
+	if err := json.NewDecoder(r.Body).Decode(entity); err != nil || entity.ID == "" {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if _, found := server.entities[entity.ID]; found {
+		http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+		return
+	}
+	if entity.Name == "" {
+		entity.Name = entity.ID
+	}
+	server.entities[entity.ID] = entity
+	writeJSON(w, http.StatusOK, entity)
+}
+
+func (server *Server) getEntity(w http.ResponseWriter, r *http.Request) {
+	server.withEntity(w, r, func(entity *wit.Entity) {
+		writeJSON(w, http.StatusOK, entity)
+	})
+}
+
+func (server *Server) updateEntity(w http.ResponseWriter, r *http.Request) {
+	server.withEntity(w, r, func(entity *wit.Entity) {
+		patch := &wit.EntityPatch{}
+		if err := json.NewDecoder(r.Body).Decode(patch); err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if patch.Doc != nil {
+			entity.Doc = *patch.Doc
+		}
+		if patch.Values != nil {
+			entity.Values = *patch.Values
+		}
+		writeJSON(w, http.StatusOK, entity)
+	})
+}
+
+func (server *Server) deleteEntity(w http.ResponseWriter, r *http.Request) {
+	server.withEntity(w, r, func(entity *wit.Entity) {
+		delete(server.entities, entity.ID)
+		writeJSON(w, http.StatusOK, map[string]string{"deleted": entity.ID})
+	})
+}
+
+func (server *Server) createEntityValue(w http.ResponseWriter, r *http.Request) {
+	server.withEntity(w, r, func(entity *wit.Entity) {
+		value := wit.EntityValue{}
+		if err := json.NewDecoder(r.Body).Decode(&value); err != nil || value.Value == "" {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if index := findValue(entity, value.Value); index >= 0 {
+			entity.Values[index].Expressions = appendMissing(entity.Values[index].Expressions, value.Expressions...)
+		} else {
+			entity.Values = append(entity.Values, value)
+		}
+		writeJSON(w, http.StatusOK, entity)
+	})
+}
+
+func (server *Server) deleteEntityValue(w http.ResponseWriter, r *http.Request) {
+	server.withValue(w, r, func(entity *wit.Entity, index int) {
+		value := entity.Values[index].Value
+		entity.Values = append(entity.Values[:index], entity.Values[index+1:]...)
+		writeJSON(w, http.StatusOK, map[string]string{"deleted": value})
+	})
+}
+
+func (server *Server) createExpression(w http.ResponseWriter, r *http.Request) {
+	server.withValue(w, r, func(entity *wit.Entity, index int) {
+		expression := wit.Expression{}
+		if err := json.NewDecoder(r.Body).Decode(&expression); err != nil || expression.Expression == "" {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		entity.Values[index].Expressions = appendMissing(entity.Values[index].Expressions, expression.Expression)
+		writeJSON(w, http.StatusOK, entity)
+	})
+}
+
+func (server *Server) deleteExpression(w http.ResponseWriter, r *http.Request) {
+	server.withValue(w, r, func(entity *wit.Entity, index int) {
+		exp := r.PathValue("exp")
+		expressions := entity.Values[index].Expressions
+		for i, expression := range expressions {
+			if expression == exp {
+				entity.Values[index].Expressions = append(expressions[:i], expressions[i+1:]...)
+				writeJSON(w, http.StatusOK, map[string]string{"deleted": exp})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+}
+
+// Looks up the entity named in the path, responding with a 404 when it is
+// missing, and calls fn with the lock held
+func (server *Server) withEntity(w http.ResponseWriter, r *http.Request, fn func(*wit.Entity)) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	entity, found := server.entities[r.PathValue("id")]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	fn(entity)
+}
+
+// Looks up the entity and value named in the path, responding with a 404 when
+// either is missing, and calls fn with the lock held
+func (server *Server) withValue(w http.ResponseWriter, r *http.Request, fn func(*wit.Entity, int)) {
+	server.withEntity(w, r, func(entity *wit.Entity) {
+		index := findValue(entity, r.PathValue("value"))
+		if index < 0 {
+			http.NotFound(w, r)
+			return
+		}
+		fn(entity, index)
+	})
+}
+
+func findValue(entity *wit.Entity, value string) int {
+	for i, entityValue := range entity.Values {
+		if entityValue.Value == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func appendMissing(expressions []string, additions ...string) []string {
+	for _, addition := range additions {
+		found := false
+		for _, expression := range expressions {
+			if expression == addition {
+				found = true
+				break
+			}
+		}
+		if !found {
+			expressions = append(expressions, addition)
+		}
+	}
+	return expressions
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,336 @@
+// Copyright (c) 2014 Jason Goecke
+// server_test.go
+
+package wittest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jsgoecke/go-wit"
+)
+
+func TestNewTestServer(t *testing.T) {
+	var server *Server
+	t.Run("test", func(t *testing.T) {
+		var client *wit.Client
+		server, client = NewTestServer(t)
+		if _, err := client.Intents(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if _, err := server.Client().Intents(); err == nil {
+		t.Error("The server should be closed when the test ends")
+	}
+}
+
+func TestServerMessage(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting", Confidence: 0.9}}})
+	client := server.Client()
+
+	message, err := client.Message(&wit.MessageRequest{Query: "hello", Context: &wit.Context{Timezone: "Europe/Paris"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "hello" || message.Outcomes[0].Intent != "greeting" || message.MsgID == "" {
+		t.Errorf("Scripted message not returned properly: %+v", message)
+	}
+	server.AssertRequested(t, "GET", "/message")
+	server.AssertQuery(t, "context", `{"timezone":"Europe/Paris"}`)
+
+	stored, err := client.Messages(message.MsgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.MsgID != message.MsgID {
+		t.Errorf("not equal %s != %s", message.MsgID, stored.MsgID)
+	}
+
+	unknown, err := client.Message(&wit.MessageRequest{Query: "something else"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unknown.Text != "something else" || len(unknown.Outcomes) != 0 {
+		t.Errorf("Default message not returned properly: %+v", unknown)
+	}
+}
+
+func TestServerAudioMessage(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetSpeech(&wit.Message{Text: "hello world"})
+	client := server.Client()
+
+	request := &wit.MessageRequest{}
+	request.File = "../audio_sample/helloWorld.wav"
+	request.ContentType = "audio/wav"
+	message, err := client.AudioMessage(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "hello world" {
+		t.Errorf("not equal %s != %s", "hello world", message.Text)
+	}
+	speech := server.AssertRequested(t, "POST", "/speech")
+	if speech.Header.Get("Content-Type") != "audio/wav" || len(speech.Body) == 0 {
+		t.Error("Audio upload was not recorded properly")
+	}
+	if speech.Header.Get("Authorization") != "Bearer wittest-token" {
+		t.Errorf("unexpected Authorization header %s", speech.Header.Get("Authorization"))
+	}
+}
+
+func TestServerEntities(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	entity := &wit.Entity{
+		ID:     "favorite_city",
+		Doc:    "A city that I like",
+		Values: []wit.EntityValue{{Value: "Paris", Expressions: []string{"Paris", "City of Light"}}},
+	}
+	created, err := client.CreateEntity(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != "favorite_city" || created.Values[0].Value != "Paris" {
+		t.Errorf("Entity was not created properly: %+v", created)
+	}
+	_, err = client.CreateEntity(entity)
+	if err == nil || err.Error() != http.StatusText(409) {
+		t.Error("Expected a 409 since the entity already exists")
+	}
+
+	_, err = client.CreateEntityValue("favorite_city", &wit.EntityValue{Value: "Barcelona", Expressions: []string{"Gaudi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := client.CreateEntityValueExp("favorite_city", "Barcelona", "Paella")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Values) != 2 || len(updated.Values[1].Expressions) != 2 {
+		t.Errorf("Values were not added properly: %+v", updated.Values)
+	}
+
+	deletion, err := client.DeleteEntityValueExp("favorite_city", "Paris", "City of Light")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deletion.Deleted != "City of Light" {
+		t.Errorf("Unexpected deletion %+v", deletion)
+	}
+	server.AssertRequested(t, "DELETE", "/entities/favorite_city/values/Paris/expressions/City of Light")
+	deletion, err = client.DeleteEntityValue("favorite_city", "Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deletion.Deleted != "Paris" || strings.TrimSpace(string(deletion.Raw)) != `{"deleted":"Paris"}` {
+		t.Errorf("Unexpected deletion %+v", deletion)
+	}
+	if _, err = client.DeleteEntityValue("favorite_city", "Paris"); err == nil || err.Error() != http.StatusText(404) {
+		t.Error("Deleting a missing value should have returned a not found error")
+	}
+
+	fetched, err := client.Entity("favorite_city")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched.Values) != 1 || fetched.Values[0].Value != "Barcelona" {
+		t.Errorf("Entity state not kept properly: %+v", fetched.Values)
+	}
+
+	entities, err := client.Entities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*entities) != 1 || (*entities)[0] != "favorite_city" {
+		t.Errorf("Entities returned not expected: %v", *entities)
+	}
+
+	if err = client.DeleteEntity("favorite_city"); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.DeleteEntity("favorite_city"); err == nil || err.Error() != http.StatusText(404) {
+		t.Error("Delete should have returned a not found error")
+	}
+}
+
+func TestServerUpdateEntity(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	server.AddEntity(&wit.Entity{ID: "favorite_city", Doc: "A city", Values: []wit.EntityValue{{Value: "Paris", Expressions: []string{"Paris"}}}})
+
+	doc := "A city I like"
+	patched, err := client.PatchEntity("favorite_city", &wit.EntityPatch{Doc: &doc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Doc != doc || len(patched.Values) != 1 {
+		t.Errorf("Entity was not patched properly: %+v", patched)
+	}
+	request := server.AssertRequested(t, "PUT", "/entities/favorite_city")
+	if string(request.Body) != `{"doc":"A city I like"}` {
+		t.Errorf("Patch should only send the changed fields, sent %s", request.Body)
+	}
+
+	values := []wit.EntityValue{{Value: "Rome", Expressions: []string{"Rome"}}}
+	patched, err = client.PatchEntity("favorite_city", &wit.EntityPatch{Values: &values})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Doc != doc || len(patched.Values) != 1 || patched.Values[0].Value != "Rome" {
+		t.Errorf("Entity was not patched properly: %+v", patched)
+	}
+
+	updated, err := client.UpdateEntity(&wit.Entity{ID: "favorite_city", Values: values})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Doc != "" || updated.ID != "favorite_city" || updated.Values[0].Value != "Rome" {
+		t.Errorf("Entity was not updated properly: %+v", updated)
+	}
+
+	if _, err = client.PatchEntity("missing", &wit.EntityPatch{Doc: &doc}); err == nil || err.Error() != http.StatusText(404) {
+		t.Error("Patching a missing entity should have returned a not found error")
+	}
+}
+
+func TestServerIntents(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddIntent("1234", "recover_password", "Recover password")
+	client := server.Client()
+
+	intents, err := client.Intents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*intents) != 1 || (*intents)[0].Name != "recover_password" {
+		t.Errorf("Intents returned not expected: %v", *intents)
+	}
+}
+
+func TestServerPagination(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		server.AddIntent(name, name, "")
+		server.AddEntity(&wit.Entity{ID: name})
+	}
+	client := server.Client()
+
+	intents, err := client.ListIntents(&wit.ListOptions{Limit: 2}).Collect(context.Background())
+	if err != nil || len(intents) != 3 || intents[2].Name != "c" {
+		t.Errorf("ListIntents = %v, %v", intents, err)
+	}
+	ids, err := client.ListEntities(&wit.ListOptions{Limit: 2, Offset: 1}).Collect(context.Background())
+	if err != nil || len(ids) != 2 || ids[0] != "b" {
+		t.Errorf("ListEntities = %v, %v", ids, err)
+	}
+	if len(server.Requested("GET", "/intents")) != 2 {
+		t.Errorf("expected 2 intent pages, got %v", server.Requested("GET", "/intents"))
+	}
+
+	server.AddApp(&wit.App{ID: "1", Name: "weather"})
+	server.AddApp(&wit.App{ID: "2", Name: "alarms"})
+	server.AddUtterance(&wit.Utterance{Text: "hello", Intent: &wit.UtteranceIntent{Name: "greeting"}})
+	apps, err := client.ListApps(&wit.ListOptions{Limit: 1, Offset: 1}).Collect(context.Background())
+	if err != nil || len(apps) != 1 || apps[0].Name != "alarms" {
+		t.Errorf("ListApps = %v, %v", apps, err)
+	}
+	utterances, err := client.ListUtterances(nil).Collect(context.Background())
+	if err != nil || len(utterances) != 1 || utterances[0].Intent.Name != "greeting" {
+		t.Errorf("ListUtterances = %v, %v", utterances, err)
+	}
+}
+
+func TestServerScript(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddEntity(&wit.Entity{ID: "wit$temperature", Builtin: true})
+	server.Script("GET", "/entities/wit$temperature", 500, "")
+	client := server.Client()
+
+	_, err := client.Entity("wit$temperature")
+	if err == nil || err.Error() != http.StatusText(500) {
+		t.Errorf("Expected the scripted 500, got %v", err)
+	}
+	entity, err := client.Entity("wit$temperature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entity.Builtin {
+		t.Error("Did not fall back to the in-memory entity")
+	}
+	server.AssertRequestCount(t, 2)
+	server.AssertNotRequested(t, "DELETE", "/entities/wit$temperature")
+
+	server.Reset()
+	server.AssertRequestCount(t, 0)
+}
+
+func TestServerIntercept(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddMessage("hello", &wit.Message{Outcomes: []wit.Outcome{{Intent: "greeting"}}})
+	server.Intercept("GET", "/message", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc")
+		if r.URL.Query().Get("q") == "flaky" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	client := server.Client()
+
+	if _, err := client.Message(&wit.MessageRequest{Query: "flaky"}); err == nil || err.Error() != http.StatusText(503) {
+		t.Errorf("Expected the intercepted 503, got %v", err)
+	}
+	response := &wit.Response{}
+	ctx := wit.WithResponse(context.Background(), response)
+	message, err := client.MessageWithContext(ctx, &wit.MessageRequest{Query: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Outcomes[0].Intent != "greeting" || response.RequestID != "abc" {
+		t.Errorf("Did not fall through to the in-memory message: %+v, %+v", message, response)
+	}
+
+	server.Intercept("GET", "/message", nil)
+	if _, err = client.Message(&wit.MessageRequest{Query: "flaky"}); err != nil {
+		t.Errorf("Removed hook still called: %v", err)
+	}
+	server.AssertRequestCount(t, 3)
+
+	server.Intercept("GET", "/intents", func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	})
+	if _, err = client.Intents(); err == nil {
+		t.Error("Expected a network error from the hijacked connection")
+	}
+}
+
+func TestServerAddEntityCopies(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	entity := &wit.Entity{ID: "favorite_city", Values: []wit.EntityValue{{Value: "Paris", Expressions: []string{"Paris"}}}}
+	server.AddEntity(entity)
+	client := server.Client()
+
+	if _, err := client.CreateEntityValueExp("favorite_city", "Paris", "City of Light"); err != nil {
+		t.Fatal(err)
+	}
+	doc := "A city that I like"
+	if _, err := client.PatchEntity("favorite_city", &wit.EntityPatch{Doc: &doc}); err != nil {
+		t.Fatal(err)
+	}
+	if entity.Doc != "" || len(entity.Values[0].Expressions) != 1 {
+		t.Errorf("The server modified the added entity: %+v", entity)
+	}
+}